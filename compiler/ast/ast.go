@@ -3,6 +3,7 @@ package ast
 import (
 	"fmt"
 	"github.com/defiant00/char/compiler/token"
+	"path"
 	"strings"
 )
 
@@ -33,8 +34,16 @@ func (this *FunctionDef) isExpr()    {}
 func (this *FunctionSig) isExpr()    {}
 func (this *Identifier) isExpr()     {}
 func (this *Iota) isExpr()           {}
+func (this *MapEntry) isExpr()       {}
+func (this *MapValueList) isExpr()   {}
+func (this *Nil) isExpr()            {}
 func (this *Number) isExpr()         {}
+func (this *Propagate) isExpr()      {}
+func (this *RangePattern) isExpr()   {}
+func (this *Receive) isExpr()        {}
+func (this *ShapePattern) isExpr()   {}
 func (this *String) isExpr()         {}
+func (this *TypePattern) isExpr()    {}
 func (this *Unary) isExpr()          {}
 
 // Statements
@@ -56,29 +65,39 @@ func (this *Iota) isStmt()        {}
 func (this *Is) isStmt()          {}
 func (this *KeyVal) isStmt()      {}
 func (this *Loop) isStmt()        {}
+func (this *Optional) isStmt()    {}
 func (this *PropertySet) isStmt() {}
 func (this *Return) isStmt()      {}
+func (this *Select) isStmt()      {}
+func (this *SelectCase) isStmt()  {}
+func (this *Send) isStmt()        {}
+func (this *Spawn) isStmt()       {}
 func (this *TypeIdent) isStmt()   {}
 func (this *Use) isStmt()         {}
 func (this *VarSet) isStmt()      {}
 func (this *VarSetLine) isStmt()  {}
 
 type Accessor struct {
+	Pos    token.Position
 	Object Expression
 	Index  Expression
 }
 
 type AccessorRange struct {
+	Pos       token.Position
 	Object    Expression
 	Low, High Expression
 }
 
 type Alias struct {
-	Val   Statement
-	Alias string
+	Pos     token.Position
+	Private bool
+	Val     Statement
+	Alias   string
 }
 
 type ArrayCons struct {
+	Pos  token.Position
 	Type Statement
 	Size Expression
 }
@@ -88,6 +107,7 @@ func (this *ArrayCons) String() string {
 }
 
 type Array struct {
+	Pos  token.Position
 	Type Statement
 }
 
@@ -96,43 +116,54 @@ func (this *Array) String() string {
 }
 
 type ArrayValueList struct {
+	Pos  token.Position
 	Vals Expression
 }
 
 type Assign struct {
+	Pos         token.Position
 	Left, Right Expression
 	Op          token.Type
 }
 
 type Binary struct {
+	Pos         token.Position
 	Left, Right Expression
 	Op          token.Type
 }
 
-type Blank struct{}
+type Blank struct {
+	Pos token.Position
+}
 
 type Bool struct {
+	Pos token.Position
 	Val bool
 }
 
 type Break struct {
+	Pos   token.Position
 	Label string
 }
 
 type Char struct {
+	Pos token.Position
 	Val string
 }
 
 type Class struct {
+	Pos        token.Position
 	Mixin      bool
+	Enum       bool // declared as an enum, lowered to iota constants
+	Private    bool // only visible in the package that declares it
 	Name       string
-	typeParams []string
+	typeParams []TypeParam
 	withs      []Statement
 	statements []Statement
 }
 
-func (this *Class) AddTypeParam(t string) {
-	this.typeParams = append(this.typeParams, t)
+func (this *Class) AddTypeParam(name string, bound Statement) {
+	this.typeParams = append(this.typeParams, TypeParam{name: name, bound: bound})
 }
 
 func (this *Class) AddWith(s Statement) {
@@ -143,7 +174,20 @@ func (this *Class) AddStmt(s Statement) {
 	this.statements = append(this.statements, s)
 }
 
+func (this *Class) TypeParams() []TypeParam {
+	return this.typeParams
+}
+
+func (this *Class) Withs() []Statement {
+	return this.withs
+}
+
+func (this *Class) Stmts() []Statement {
+	return this.statements
+}
+
 type Constructor struct {
+	Pos    token.Position
 	Type   Expression
 	params []Statement
 }
@@ -152,15 +196,22 @@ func (this *Constructor) AddParam(p Statement) {
 	this.params = append(this.params, p)
 }
 
+func (this *Constructor) Params() []Statement {
+	return this.params
+}
+
 type Defer struct {
+	Pos  token.Position
 	Expr Expression
 }
 
 type Error struct {
+	Pos token.Position
 	Val string
 }
 
 type ExprList struct {
+	Pos   token.Position
 	exprs []Expression
 }
 
@@ -168,7 +219,12 @@ func (this *ExprList) AddExpr(e Expression) {
 	this.exprs = append(this.exprs, e)
 }
 
+func (this *ExprList) Exprs() []Expression {
+	return this.exprs
+}
+
 type ExprStmt struct {
+	Pos  token.Position
 	Expr Expression
 }
 
@@ -181,7 +237,12 @@ func (this *File) AddStmt(stmt Statement) {
 	this.statements = append(this.statements, stmt)
 }
 
+func (this *File) Stmts() []Statement {
+	return this.statements
+}
+
 type For struct {
+	Pos   token.Position
 	Label string
 	vars  []string
 	In    Expression
@@ -196,15 +257,27 @@ func (this *For) AddStmt(s Statement) {
 	this.stmts = append(this.stmts, s)
 }
 
+func (this *For) Vars() []string {
+	return this.vars
+}
+
+func (this *For) Stmts() []Statement {
+	return this.stmts
+}
+
 type FunctionCall struct {
+	Pos      token.Position
 	Function Expression
 	Params   Expression
 }
 
 type FunctionDef struct {
+	Pos        token.Position
 	Static     bool
+	Native     bool // implemented by the runtime, so it has no body
+	Private    bool
 	Name       string
-	params     []param
+	params     []Param
 	returns    []Statement
 	statements []Statement
 }
@@ -214,14 +287,27 @@ func (this *FunctionDef) AddStmt(s Statement) {
 }
 
 func (this *FunctionDef) AddParam(name string, typ Statement) {
-	this.params = append(this.params, param{name: name, typ: typ})
+	this.params = append(this.params, Param{name: name, typ: typ})
 }
 
 func (this *FunctionDef) AddReturn(r Statement) {
 	this.returns = append(this.returns, r)
 }
 
+func (this *FunctionDef) Params() []Param {
+	return this.params
+}
+
+func (this *FunctionDef) Returns() []Statement {
+	return this.returns
+}
+
+func (this *FunctionDef) Stmts() []Statement {
+	return this.statements
+}
+
 type FunctionSig struct {
+	Pos     token.Position
 	params  []Statement
 	returns []Statement
 }
@@ -261,7 +347,16 @@ func (this *FunctionSig) AddReturn(r Statement) {
 	this.returns = append(this.returns, r)
 }
 
+func (this *FunctionSig) Params() []Statement {
+	return this.params
+}
+
+func (this *FunctionSig) Returns() []Statement {
+	return this.returns
+}
+
 type Identifier struct {
+	Pos    token.Position
 	idents []*IdentPart
 }
 
@@ -269,7 +364,12 @@ func (this *Identifier) AddIdent(i *IdentPart) {
 	this.idents = append(this.idents, i)
 }
 
+func (this *Identifier) Idents() []*IdentPart {
+	return this.idents
+}
+
 type IdentPart struct {
+	Pos        token.Position
 	Name       string
 	typeParams []Statement
 }
@@ -291,6 +391,10 @@ func (this *IdentPart) String() string {
 	return ret
 }
 
+func (this *IdentPart) TypeParams() []Statement {
+	return this.typeParams
+}
+
 func (this *IdentPart) AddTypeParam(s Statement) {
 	this.typeParams = append(this.typeParams, s)
 }
@@ -300,6 +404,7 @@ func (this *IdentPart) ResetTypeParams() {
 }
 
 type If struct {
+	Pos       token.Position
 	Condition Expression
 	With      Statement
 	stmts     []Statement
@@ -309,7 +414,13 @@ func (this *If) AddStmt(s Statement) {
 	this.stmts = append(this.stmts, s)
 }
 
+func (this *If) Stmts() []Statement {
+	return this.stmts
+}
+
 type Interface struct {
+	Pos      token.Position
+	Private  bool
 	Name     string
 	withs    []Statement
 	funcSigs []Statement
@@ -323,7 +434,16 @@ func (this *Interface) AddFuncSig(i Statement) {
 	this.funcSigs = append(this.funcSigs, i)
 }
 
+func (this *Interface) Withs() []Statement {
+	return this.withs
+}
+
+func (this *Interface) FuncSigs() []Statement {
+	return this.funcSigs
+}
+
 type IntfFuncSig struct {
+	Pos     token.Position
 	Name    string
 	params  []Statement
 	returns []Statement
@@ -364,9 +484,20 @@ func (this *IntfFuncSig) AddReturn(r Statement) {
 	this.returns = append(this.returns, r)
 }
 
-type Iota struct{}
+func (this *IntfFuncSig) Params() []Statement {
+	return this.params
+}
+
+func (this *IntfFuncSig) Returns() []Statement {
+	return this.returns
+}
+
+type Iota struct {
+	Pos token.Position
+}
 
 type Is struct {
+	Pos       token.Position
 	Condition Expression
 	stmts     []Statement
 }
@@ -375,12 +506,18 @@ func (this *Is) AddStmt(s Statement) {
 	this.stmts = append(this.stmts, s)
 }
 
+func (this *Is) Stmts() []Statement {
+	return this.stmts
+}
+
 type KeyVal struct {
+	Pos token.Position
 	Key string
 	Val Expression
 }
 
 type Loop struct {
+	Pos   token.Position
 	Label string
 	stmts []Statement
 }
@@ -389,16 +526,56 @@ func (this *Loop) AddStmt(s Statement) {
 	this.stmts = append(this.stmts, s)
 }
 
+func (this *Loop) Stmts() []Statement {
+	return this.stmts
+}
+
+// MapEntry is a key and the value it maps to in a MapValueList.
+type MapEntry struct {
+	Pos      token.Position
+	Key, Val Expression
+}
+
+// MapValueList is a map literal, its Vals a list of MapEntry.
+type MapValueList struct {
+	Pos  token.Position
+	Vals Expression
+}
+
+// Nil is the value of an interface or optional that holds nothing.
+type Nil struct {
+	Pos token.Position
+}
+
 type Number struct {
+	Pos token.Position
 	Val string
 }
 
-type param struct {
+// Optional is a type that holds either a value of Type or nil.
+type Optional struct {
+	Pos  token.Position
+	Type Statement
+}
+
+func (this *Optional) String() string {
+	return fmt.Sprintf("%v?", this.Type)
+}
+
+type Param struct {
 	name string
 	typ  Statement
 }
 
-func (this param) String() string {
+func (this Param) Name() string {
+	return this.name
+}
+
+func (this Param) Type() Statement {
+	return this.typ
+}
+
+func (this Param) String() string {
 	ret := this.name
 	if this.typ != nil {
 		ret += fmt.Sprint(" ", this.typ)
@@ -406,13 +583,25 @@ func (this param) String() string {
 	return ret
 }
 
-type property struct {
+type Property struct {
 	static bool
 	name   string
 	typ    Statement
 }
 
-func (this property) String() string {
+func (this Property) Static() bool {
+	return this.static
+}
+
+func (this Property) Name() string {
+	return this.name
+}
+
+func (this Property) Type() Statement {
+	return this.typ
+}
+
+func (this Property) String() string {
 	var ret string
 	if this.static {
 		ret = "static "
@@ -425,27 +614,122 @@ func (this property) String() string {
 }
 
 type PropertySet struct {
-	props []property
-	Vals  Expression
+	Pos     token.Position
+	Private bool
+	props   []Property
+	Vals    Expression
 }
 
 func (this *PropertySet) AddProp(static bool, name string, typ Statement) {
-	this.props = append(this.props, property{static: static, name: name, typ: typ})
+	this.props = append(this.props, Property{static: static, name: name, typ: typ})
+}
+
+func (this *PropertySet) Props() []Property {
+	return this.props
+}
+
+// Propagate is a call followed by ?, which returns from the function early
+// if the last result of the call, an Error, isn't nil. Otherwise its value
+// is the other results.
+type Propagate struct {
+	Pos  token.Position
+	Expr Expression
+}
+
+// RangePattern matches the values from Low to High, inclusive.
+type RangePattern struct {
+	Pos       token.Position
+	Low, High Expression
+}
+
+// Receive is <-Chan, the next value sent on a channel.
+type Receive struct {
+	Pos  token.Position
+	Chan Expression
 }
 
 type Return struct {
+	Pos  token.Position
 	Vals Expression
 }
 
+// Select waits until one of its cases can send or receive, then runs it.
+// Its statements are SelectCases.
+type Select struct {
+	Pos   token.Position
+	stmts []Statement
+}
+
+func (this *Select) AddStmt(s Statement) {
+	this.stmts = append(this.stmts, s)
+}
+
+func (this *Select) Stmts() []Statement {
+	return this.stmts
+}
+
+// SelectCase is a case of a select. Comm is a Send, or an ExprStmt, Assign
+// or VarSet that receives, and nil for is _, which runs when no other case
+// is ready.
+type SelectCase struct {
+	Pos   token.Position
+	Comm  Statement
+	stmts []Statement
+}
+
+func (this *SelectCase) AddStmt(s Statement) {
+	this.stmts = append(this.stmts, s)
+}
+
+func (this *SelectCase) Stmts() []Statement {
+	return this.stmts
+}
+
+// Send is Chan <- Val.
+type Send struct {
+	Pos       token.Position
+	Chan, Val Expression
+}
+
+// Spawn runs the call Expr concurrently.
+type Spawn struct {
+	Pos  token.Position
+	Expr Expression
+}
+
+// ShapePattern matches an instance of Type whose properties match the
+// patterns of its fields. A field without a pattern binds the property to a
+// variable of the same name.
+type ShapePattern struct {
+	Pos    token.Position
+	Type   Statement
+	fields []*KeyVal
+}
+
+func (this *ShapePattern) AddField(f *KeyVal) {
+	this.fields = append(this.fields, f)
+}
+
+func (this *ShapePattern) Fields() []*KeyVal {
+	return this.fields
+}
+
 type String struct {
+	Pos token.Position
 	Val string
 }
 
 type TypeIdent struct {
+	Pos        token.Position
 	idents     []string
 	typeParams []Statement
 }
 
+// NewTypeIdent returns a type identifier for the single name, such as string.
+func NewTypeIdent(pos token.Position, name string) *TypeIdent {
+	return &TypeIdent{Pos: pos, idents: []string{name}}
+}
+
 func (this *TypeIdent) String() string {
 	ret := strings.Join(this.idents, ".")
 	if len(this.typeParams) > 0 {
@@ -471,36 +755,104 @@ func (this *TypeIdent) AddTypeParam(s Statement) {
 	this.typeParams = append(this.typeParams, s)
 }
 
+func (this *TypeIdent) Idents() []string {
+	return this.idents
+}
+
+func (this *TypeIdent) TypeParams() []Statement {
+	return this.typeParams
+}
+
+// TypeParam is a type parameter of a generic class, with the interface its
+// type arguments must implement if it has one.
+// TypePattern matches a value of type Type and binds it to Name.
+type TypePattern struct {
+	Pos  token.Position
+	Type Statement
+	Name string
+}
+
+type TypeParam struct {
+	name  string
+	bound Statement
+}
+
+func (this TypeParam) Name() string {
+	return this.name
+}
+
+func (this TypeParam) Bound() Statement {
+	return this.bound
+}
+
+func (this TypeParam) String() string {
+	if this.bound != nil {
+		return fmt.Sprintf("%v %v", this.name, this.bound)
+	}
+	return this.name
+}
+
 type Unary struct {
+	Pos  token.Position
 	Expr Expression
 	Op   token.Type
 }
 
 type Use struct {
-	packages []usePackage
+	Pos      token.Position
+	packages []UsePackage
 }
 
 func (this *Use) AddPackage(pack, alias string) {
-	this.packages = append(this.packages, usePackage{pack, alias})
+	this.packages = append(this.packages, UsePackage{pack, alias})
+}
+
+func (this *Use) Packages() []UsePackage {
+	return this.packages
 }
 
-type usePackage struct {
+type UsePackage struct {
 	pack, alias string
 }
 
-func (this usePackage) String() string {
+func (this UsePackage) Package() string {
+	return this.pack
+}
+
+func (this UsePackage) Alias() string {
+	return this.alias
+}
+
+// Name returns the name the package is referred to by: its alias, or else
+// the last element of its path.
+func (this UsePackage) Name() string {
+	if this.alias != "" {
+		return this.alias
+	}
+	return path.Base(this.pack)
+}
+
+func (this UsePackage) String() string {
 	if this.alias != "" {
 		return fmt.Sprintf("%v as %v", this.pack, this.alias)
 	}
 	return this.pack
 }
 
-type variable struct {
+type Variable struct {
 	name string
 	typ  Statement
 }
 
-func (this variable) String() string {
+func (this Variable) Name() string {
+	return this.name
+}
+
+func (this Variable) Type() Statement {
+	return this.typ
+}
+
+func (this Variable) String() string {
 	ret := this.name
 	if this.typ != nil {
 		ret += fmt.Sprint(" ", this.typ)
@@ -509,6 +861,7 @@ func (this variable) String() string {
 }
 
 type VarSet struct {
+	Pos   token.Position
 	lines []*VarSetLine
 }
 
@@ -516,11 +869,20 @@ func (this *VarSet) AddLine(vsl *VarSetLine) {
 	this.lines = append(this.lines, vsl)
 }
 
+func (this *VarSet) Lines() []*VarSetLine {
+	return this.lines
+}
+
 type VarSetLine struct {
-	vars []variable
+	Pos  token.Position
+	vars []Variable
 	Vals Expression
 }
 
 func (this *VarSetLine) AddVar(name string, typ Statement) {
-	this.vars = append(this.vars, variable{name: name, typ: typ})
+	this.vars = append(this.vars, Variable{name: name, typ: typ})
+}
+
+func (this *VarSetLine) Vars() []Variable {
+	return this.vars
 }
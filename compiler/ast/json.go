@@ -0,0 +1,372 @@
+package ast
+
+import "github.com/defiant00/char/compiler/token"
+
+// JSONVersion is bumped whenever the shape produced by JSON changes in a way
+// that isn't purely additive.
+const JSONVersion = 2
+
+// JSON converts obj into a tree of maps and slices ready for encoding/json.
+//
+// Every node becomes an object with a "kind" (the Go type name, e.g.
+// "Binary"), a "pos" ({"line", "char"}) and one key per field, named after
+// the field in lower camel case. Child nodes are nested objects, lists of
+// children are arrays, operators are their token strings and absent children
+// are null. Files and packages have no position.
+func JSON(obj General) interface{} {
+	if obj == nil {
+		return nil
+	}
+	switch this := obj.(type) {
+	case *Accessor:
+		return jsonNode("Accessor", this.Pos, map[string]interface{}{
+			"object": JSON(this.Object),
+			"index":  JSON(this.Index),
+		})
+	case *AccessorRange:
+		return jsonNode("AccessorRange", this.Pos, map[string]interface{}{
+			"object": JSON(this.Object),
+			"low":    JSON(this.Low),
+			"high":   JSON(this.High),
+		})
+	case *Alias:
+		return jsonNode("Alias", this.Pos, map[string]interface{}{
+			"private": this.Private,
+			"val":     JSON(this.Val),
+			"alias":   this.Alias,
+		})
+	case *ArrayCons:
+		return jsonNode("ArrayCons", this.Pos, map[string]interface{}{
+			"type": JSON(this.Type),
+			"size": JSON(this.Size),
+		})
+	case *Array:
+		return jsonNode("Array", this.Pos, map[string]interface{}{
+			"type": JSON(this.Type),
+		})
+	case *ArrayValueList:
+		return jsonNode("ArrayValueList", this.Pos, map[string]interface{}{
+			"vals": JSON(this.Vals),
+		})
+	case *Assign:
+		return jsonNode("Assign", this.Pos, map[string]interface{}{
+			"op":    this.Op,
+			"left":  JSON(this.Left),
+			"right": JSON(this.Right),
+		})
+	case *Binary:
+		return jsonNode("Binary", this.Pos, map[string]interface{}{
+			"op":    this.Op,
+			"left":  JSON(this.Left),
+			"right": JSON(this.Right),
+		})
+	case *Blank:
+		return jsonNode("Blank", this.Pos, nil)
+	case *Bool:
+		return jsonNode("Bool", this.Pos, map[string]interface{}{
+			"val": this.Val,
+		})
+	case *Break:
+		return jsonNode("Break", this.Pos, map[string]interface{}{
+			"label": this.Label,
+		})
+	case *Char:
+		return jsonNode("Char", this.Pos, map[string]interface{}{
+			"val": this.Val,
+		})
+	case *Class:
+		typeParams := make([]interface{}, 0, len(this.typeParams))
+		for _, tp := range this.typeParams {
+			typeParams = append(typeParams, map[string]interface{}{
+				"name":  tp.name,
+				"bound": JSON(tp.bound),
+			})
+		}
+		return jsonNode("Class", this.Pos, map[string]interface{}{
+			"mixin":      this.Mixin,
+			"enum":       this.Enum,
+			"private":    this.Private,
+			"name":       this.Name,
+			"typeParams": typeParams,
+			"withs":      jsonStmts(this.withs),
+			"stmts":      jsonStmts(this.statements),
+		})
+	case *Constructor:
+		return jsonNode("Constructor", this.Pos, map[string]interface{}{
+			"type":   JSON(this.Type),
+			"params": jsonStmts(this.params),
+		})
+	case *Defer:
+		return jsonNode("Defer", this.Pos, map[string]interface{}{
+			"expr": JSON(this.Expr),
+		})
+	case *Error:
+		return jsonNode("Error", this.Pos, map[string]interface{}{
+			"val": this.Val,
+		})
+	case *ExprList:
+		exprs := make([]interface{}, 0, len(this.exprs))
+		for _, e := range this.exprs {
+			exprs = append(exprs, JSON(e))
+		}
+		return jsonNode("ExprList", this.Pos, map[string]interface{}{
+			"exprs": exprs,
+		})
+	case *ExprStmt:
+		return jsonNode("ExprStmt", this.Pos, map[string]interface{}{
+			"expr": JSON(this.Expr),
+		})
+	case *File:
+		return map[string]interface{}{
+			"kind":  "File",
+			"name":  this.Name,
+			"stmts": jsonStmts(this.statements),
+		}
+	case *For:
+		return jsonNode("For", this.Pos, map[string]interface{}{
+			"label": this.Label,
+			"vars":  jsonStrings(this.vars),
+			"in":    JSON(this.In),
+			"stmts": jsonStmts(this.stmts),
+		})
+	case *FunctionCall:
+		return jsonNode("FunctionCall", this.Pos, map[string]interface{}{
+			"function": JSON(this.Function),
+			"params":   JSON(this.Params),
+		})
+	case *FunctionDef:
+		params := make([]interface{}, 0, len(this.params))
+		for _, p := range this.params {
+			params = append(params, map[string]interface{}{
+				"name": p.name,
+				"type": JSON(p.typ),
+			})
+		}
+		return jsonNode("FunctionDef", this.Pos, map[string]interface{}{
+			"static":  this.Static,
+			"native":  this.Native,
+			"private": this.Private,
+			"name":    this.Name,
+			"params":  params,
+			"returns": jsonStmts(this.returns),
+			"stmts":   jsonStmts(this.statements),
+		})
+	case *FunctionSig:
+		return jsonNode("FunctionSig", this.Pos, map[string]interface{}{
+			"params":  jsonStmts(this.params),
+			"returns": jsonStmts(this.returns),
+		})
+	case *Identifier:
+		idents := make([]interface{}, 0, len(this.idents))
+		for _, id := range this.idents {
+			idents = append(idents, JSON(id))
+		}
+		return jsonNode("Identifier", this.Pos, map[string]interface{}{
+			"idents": idents,
+		})
+	case *IdentPart:
+		return jsonNode("IdentPart", this.Pos, map[string]interface{}{
+			"name":       this.Name,
+			"typeParams": jsonStmts(this.typeParams),
+		})
+	case *If:
+		return jsonNode("If", this.Pos, map[string]interface{}{
+			"condition": JSON(this.Condition),
+			"with":      JSON(this.With),
+			"stmts":     jsonStmts(this.stmts),
+		})
+	case *Interface:
+		return jsonNode("Interface", this.Pos, map[string]interface{}{
+			"private":  this.Private,
+			"name":     this.Name,
+			"withs":    jsonStmts(this.withs),
+			"funcSigs": jsonStmts(this.funcSigs),
+		})
+	case *IntfFuncSig:
+		return jsonNode("IntfFuncSig", this.Pos, map[string]interface{}{
+			"name":    this.Name,
+			"params":  jsonStmts(this.params),
+			"returns": jsonStmts(this.returns),
+		})
+	case *Iota:
+		return jsonNode("Iota", this.Pos, nil)
+	case *Is:
+		return jsonNode("Is", this.Pos, map[string]interface{}{
+			"condition": JSON(this.Condition),
+			"stmts":     jsonStmts(this.stmts),
+		})
+	case *KeyVal:
+		return jsonNode("KeyVal", this.Pos, map[string]interface{}{
+			"key": this.Key,
+			"val": JSON(this.Val),
+		})
+	case *Loop:
+		return jsonNode("Loop", this.Pos, map[string]interface{}{
+			"label": this.Label,
+			"stmts": jsonStmts(this.stmts),
+		})
+	case *MapEntry:
+		return jsonNode("MapEntry", this.Pos, map[string]interface{}{
+			"key": JSON(this.Key),
+			"val": JSON(this.Val),
+		})
+	case *MapValueList:
+		return jsonNode("MapValueList", this.Pos, map[string]interface{}{
+			"vals": JSON(this.Vals),
+		})
+	case *Nil:
+		return jsonNode("Nil", this.Pos, nil)
+	case *Number:
+		return jsonNode("Number", this.Pos, map[string]interface{}{
+			"val": this.Val,
+		})
+	case *Optional:
+		return jsonNode("Optional", this.Pos, map[string]interface{}{
+			"type": JSON(this.Type),
+		})
+	case *Package:
+		files := make([]interface{}, 0, len(this.files))
+		for _, f := range this.files {
+			files = append(files, JSON(f))
+		}
+		return map[string]interface{}{
+			"kind":  "Package",
+			"name":  this.Name,
+			"files": files,
+		}
+	case *PropertySet:
+		props := make([]interface{}, 0, len(this.props))
+		for _, p := range this.props {
+			props = append(props, map[string]interface{}{
+				"static": p.static,
+				"name":   p.name,
+				"type":   JSON(p.typ),
+			})
+		}
+		return jsonNode("PropertySet", this.Pos, map[string]interface{}{
+			"private": this.Private,
+			"props":   props,
+			"vals":    JSON(this.Vals),
+		})
+	case *Propagate:
+		return jsonNode("Propagate", this.Pos, map[string]interface{}{
+			"expr": JSON(this.Expr),
+		})
+	case *RangePattern:
+		return jsonNode("RangePattern", this.Pos, map[string]interface{}{
+			"low":  JSON(this.Low),
+			"high": JSON(this.High),
+		})
+	case *Receive:
+		return jsonNode("Receive", this.Pos, map[string]interface{}{
+			"chan": JSON(this.Chan),
+		})
+	case *Return:
+		return jsonNode("Return", this.Pos, map[string]interface{}{
+			"vals": JSON(this.Vals),
+		})
+	case *Select:
+		return jsonNode("Select", this.Pos, map[string]interface{}{
+			"stmts": jsonStmts(this.stmts),
+		})
+	case *SelectCase:
+		return jsonNode("SelectCase", this.Pos, map[string]interface{}{
+			"comm":  JSON(this.Comm),
+			"stmts": jsonStmts(this.stmts),
+		})
+	case *Send:
+		return jsonNode("Send", this.Pos, map[string]interface{}{
+			"chan": JSON(this.Chan),
+			"val":  JSON(this.Val),
+		})
+	case *ShapePattern:
+		fields := make([]interface{}, 0, len(this.fields))
+		for _, f := range this.fields {
+			fields = append(fields, JSON(f))
+		}
+		return jsonNode("ShapePattern", this.Pos, map[string]interface{}{
+			"type":   JSON(this.Type),
+			"fields": fields,
+		})
+	case *Spawn:
+		return jsonNode("Spawn", this.Pos, map[string]interface{}{
+			"expr": JSON(this.Expr),
+		})
+	case *String:
+		return jsonNode("String", this.Pos, map[string]interface{}{
+			"val": this.Val,
+		})
+	case *TypeIdent:
+		return jsonNode("TypeIdent", this.Pos, map[string]interface{}{
+			"idents":     jsonStrings(this.idents),
+			"typeParams": jsonStmts(this.typeParams),
+		})
+	case *TypePattern:
+		return jsonNode("TypePattern", this.Pos, map[string]interface{}{
+			"type": JSON(this.Type),
+			"name": this.Name,
+		})
+	case *Unary:
+		return jsonNode("Unary", this.Pos, map[string]interface{}{
+			"op":   this.Op,
+			"expr": JSON(this.Expr),
+		})
+	case *Use:
+		packages := make([]interface{}, 0, len(this.packages))
+		for _, p := range this.packages {
+			packages = append(packages, map[string]interface{}{
+				"package": p.pack,
+				"alias":   p.alias,
+			})
+		}
+		return jsonNode("Use", this.Pos, map[string]interface{}{
+			"packages": packages,
+		})
+	case *VarSet:
+		lines := make([]interface{}, 0, len(this.lines))
+		for _, l := range this.lines {
+			lines = append(lines, JSON(l))
+		}
+		return jsonNode("VarSet", this.Pos, map[string]interface{}{
+			"lines": lines,
+		})
+	case *VarSetLine:
+		vars := make([]interface{}, 0, len(this.vars))
+		for _, v := range this.vars {
+			vars = append(vars, map[string]interface{}{
+				"name": v.name,
+				"type": JSON(v.typ),
+			})
+		}
+		return jsonNode("VarSetLine", this.Pos, map[string]interface{}{
+			"vars": vars,
+			"vals": JSON(this.Vals),
+		})
+	}
+	return nil
+}
+
+func jsonNode(kind string, pos token.Position, fields map[string]interface{}) map[string]interface{} {
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields["kind"] = kind
+	fields["pos"] = pos
+	return fields
+}
+
+func jsonStmts(stmts []Statement) []interface{} {
+	ret := make([]interface{}, 0, len(stmts))
+	for _, s := range stmts {
+		ret = append(ret, JSON(s))
+	}
+	return ret
+}
+
+func jsonStrings(strs []string) []interface{} {
+	ret := make([]interface{}, 0, len(strs))
+	for _, s := range strs {
+		ret = append(ret, s)
+	}
+	return ret
+}
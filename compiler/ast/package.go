@@ -0,0 +1,103 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Package holds all files of a directory along with a single top-level
+// scope containing every class, mixin, interface and alias they declare.
+type Package struct {
+	Name  string
+	Scope *Scope
+	files []*File
+}
+
+func (this *Package) Files() []*File {
+	return this.files
+}
+
+// NewPackage merges the top-level declarations of files into a package scope.
+// A name declared more than once results in an error per redeclaration; the
+// first declaration wins.
+func NewPackage(name string, files []*File) (*Package, []error) {
+	pkg := &Package{Name: name, Scope: NewScope(nil)}
+	var errs []error
+	for _, f := range files {
+		pkg.files = append(pkg.files, f)
+		for _, s := range f.statements {
+			declName := DeclName(s)
+			if declName == "" {
+				continue
+			}
+			if prev := pkg.Scope.Insert(&Object{Name: declName, Decl: s, File: f}); prev != nil {
+				errs = append(errs, fmt.Errorf("%v: %v redeclared in package %v, previous declaration in %v", f.Name, declName, name, prev.File.Name))
+			}
+		}
+	}
+	return pkg, errs
+}
+
+// DeclName returns the name a top-level statement declares, or "" if it
+// doesn't declare one.
+func DeclName(s Statement) string {
+	switch t := s.(type) {
+	case *Alias:
+		return t.Alias
+	case *Class:
+		return t.Name
+	case *Interface:
+		return t.Name
+	}
+	return ""
+}
+
+// Object is a named declaration and the file it was declared in.
+type Object struct {
+	Name string
+	Decl Statement
+	File *File
+}
+
+// Scope maps names to the objects they were declared as.
+type Scope struct {
+	Outer   *Scope
+	objects map[string]*Object
+}
+
+func NewScope(outer *Scope) *Scope {
+	return &Scope{Outer: outer, objects: make(map[string]*Object)}
+}
+
+// Insert adds obj to the scope. If an object with the same name already
+// exists it is returned and the scope is left unchanged.
+func (this *Scope) Insert(obj *Object) *Object {
+	if prev, ok := this.objects[obj.Name]; ok {
+		return prev
+	}
+	this.objects[obj.Name] = obj
+	return nil
+}
+
+// Lookup finds name in this scope or any of its outer scopes.
+func (this *Scope) Lookup(name string) *Object {
+	for s := this; s != nil; s = s.Outer {
+		if obj, ok := s.objects[name]; ok {
+			return obj
+		}
+	}
+	return nil
+}
+
+// ParseErrors returns an error for every Error node in the tree rooted at n,
+// which was parsed from file, in the order they appear.
+func ParseErrors(file string, n General) []error {
+	var errs []error
+	Inspect(n, func(n General) bool {
+		if e, ok := n.(*Error); ok {
+			errs = append(errs, fmt.Errorf("%v:%v: %v", file, e.Pos, strings.TrimSpace(e.Val)))
+		}
+		return true
+	})
+	return errs
+}
@@ -0,0 +1,117 @@
+package ast
+
+import "github.com/defiant00/char/compiler/token"
+
+// PosOf returns the position of the first token of a node, or the zero
+// position if obj is nil or has no position.
+func PosOf(obj General) token.Position {
+	switch t := obj.(type) {
+	case *Accessor:
+		return t.Pos
+	case *AccessorRange:
+		return t.Pos
+	case *Alias:
+		return t.Pos
+	case *ArrayCons:
+		return t.Pos
+	case *Array:
+		return t.Pos
+	case *ArrayValueList:
+		return t.Pos
+	case *Assign:
+		return t.Pos
+	case *Binary:
+		return t.Pos
+	case *Blank:
+		return t.Pos
+	case *Bool:
+		return t.Pos
+	case *Break:
+		return t.Pos
+	case *Char:
+		return t.Pos
+	case *Class:
+		return t.Pos
+	case *Constructor:
+		return t.Pos
+	case *Defer:
+		return t.Pos
+	case *Error:
+		return t.Pos
+	case *ExprList:
+		return t.Pos
+	case *ExprStmt:
+		return t.Pos
+	case *For:
+		return t.Pos
+	case *FunctionCall:
+		return t.Pos
+	case *FunctionDef:
+		return t.Pos
+	case *FunctionSig:
+		return t.Pos
+	case *Identifier:
+		return t.Pos
+	case *IdentPart:
+		return t.Pos
+	case *If:
+		return t.Pos
+	case *Interface:
+		return t.Pos
+	case *IntfFuncSig:
+		return t.Pos
+	case *Iota:
+		return t.Pos
+	case *Is:
+		return t.Pos
+	case *KeyVal:
+		return t.Pos
+	case *Loop:
+		return t.Pos
+	case *MapEntry:
+		return t.Pos
+	case *MapValueList:
+		return t.Pos
+	case *Nil:
+		return t.Pos
+	case *Number:
+		return t.Pos
+	case *Optional:
+		return t.Pos
+	case *PropertySet:
+		return t.Pos
+	case *Propagate:
+		return t.Pos
+	case *RangePattern:
+		return t.Pos
+	case *Receive:
+		return t.Pos
+	case *Return:
+		return t.Pos
+	case *Select:
+		return t.Pos
+	case *SelectCase:
+		return t.Pos
+	case *Send:
+		return t.Pos
+	case *ShapePattern:
+		return t.Pos
+	case *Spawn:
+		return t.Pos
+	case *String:
+		return t.Pos
+	case *TypePattern:
+		return t.Pos
+	case *TypeIdent:
+		return t.Pos
+	case *Unary:
+		return t.Pos
+	case *Use:
+		return t.Pos
+	case *VarSet:
+		return t.Pos
+	case *VarSetLine:
+		return t.Pos
+	}
+	return token.Position{}
+}
@@ -2,295 +2,404 @@ package ast
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 )
 
-func printIndent(indent int) {
+func printIndent(w io.Writer, indent int) {
 	for i := 0; i < indent; i++ {
-		fmt.Print("|   ")
+		fmt.Fprint(w, "|   ")
 	}
 }
 
 func Print(obj General, indent int) {
+	Fprint(os.Stdout, obj, indent)
+}
+
+// Fprint writes the tree representation of obj to w.
+func Fprint(w io.Writer, obj General, indent int) {
 	if obj == nil {
 		return
 	}
-	printIndent(indent)
+	printIndent(w, indent)
 	switch t := obj.(type) {
 	case *Accessor:
 		this := obj.(*Accessor)
-		fmt.Println("accessor")
-		Print(this.Object, indent+2)
-		printIndent(indent + 1)
-		fmt.Println("index")
-		Print(this.Index, indent+2)
+		fmt.Fprintln(w, "accessor")
+		Fprint(w, this.Object, indent+2)
+		printIndent(w, indent+1)
+		fmt.Fprintln(w, "index")
+		Fprint(w, this.Index, indent+2)
 	case *AccessorRange:
 		this := obj.(*AccessorRange)
-		fmt.Println("range accessor")
-		Print(this.Object, indent+2)
-		printIndent(indent + 1)
-		fmt.Println("from")
+		fmt.Fprintln(w, "range accessor")
+		Fprint(w, this.Object, indent+2)
+		printIndent(w, indent+1)
+		fmt.Fprintln(w, "from")
 		if this.Low != nil {
-			Print(this.Low, indent+2)
+			Fprint(w, this.Low, indent+2)
 		} else {
-			printIndent(indent + 2)
-			fmt.Println("implicit 0")
+			printIndent(w, indent+2)
+			fmt.Fprintln(w, "implicit 0")
 		}
-		printIndent(indent + 1)
-		fmt.Println("to")
+		printIndent(w, indent+1)
+		fmt.Fprintln(w, "to")
 		if this.High != nil {
-			Print(this.High, indent+2)
+			Fprint(w, this.High, indent+2)
 		} else {
-			printIndent(indent + 2)
-			fmt.Println("implicit length - 1")
+			printIndent(w, indent+2)
+			fmt.Fprintln(w, "implicit length - 1")
 		}
 	case *Alias:
 		this := obj.(*Alias)
-		fmt.Printf("%v as %v\n", this.Val, this.Alias)
+		if this.Private {
+			fmt.Fprint(w, "priv ")
+		}
+		fmt.Fprintf(w, "%v as %v\n", this.Val, this.Alias)
 	case *ArrayCons:
 		this := obj.(*ArrayCons)
-		fmt.Println(this)
-		Print(this.Size, indent+1)
+		fmt.Fprintln(w, this)
+		Fprint(w, this.Size, indent+1)
 	case *Array:
-		fmt.Println(obj)
+		fmt.Fprintln(w, obj)
 	case *ArrayValueList:
-		fmt.Println("array val list")
-		Print(obj.(*ArrayValueList).Vals, indent+1)
+		fmt.Fprintln(w, "array val list")
+		Fprint(w, obj.(*ArrayValueList).Vals, indent+1)
 	case *Assign:
 		this := obj.(*Assign)
-		fmt.Println("assign", this.Op)
-		Print(this.Left, indent+1)
-		Print(this.Right, indent+1)
+		fmt.Fprintln(w, "assign", this.Op)
+		Fprint(w, this.Left, indent+1)
+		Fprint(w, this.Right, indent+1)
 	case *Binary:
 		this := obj.(*Binary)
-		fmt.Println(this.Op)
-		Print(this.Left, indent+1)
-		Print(this.Right, indent+1)
+		fmt.Fprintln(w, this.Op)
+		Fprint(w, this.Left, indent+1)
+		Fprint(w, this.Right, indent+1)
 	case *Blank:
-		fmt.Println("_")
+		fmt.Fprintln(w, "_")
 	case *Bool:
-		fmt.Println("bool", obj.(*Bool).Val)
+		fmt.Fprintln(w, "bool", obj.(*Bool).Val)
 	case *Break:
-		fmt.Println("break", obj.(*Break).Label)
+		fmt.Fprintln(w, "break", obj.(*Break).Label)
 	case *Char:
-		fmt.Printf("char '%v'\n", obj.(*Char).Val)
+		fmt.Fprintf(w, "char '%v'\n", obj.(*Char).Val)
 	case *Class:
 		this := obj.(*Class)
+		if this.Private {
+			fmt.Fprint(w, "priv ")
+		}
 		if this.Mixin {
-			fmt.Print("mixin ")
+			fmt.Fprint(w, "mixin ")
+		}
+		if this.Enum {
+			fmt.Fprint(w, "enum ")
 		}
-		fmt.Print("class ", this.Name)
+		fmt.Fprint(w, "class ", this.Name)
 		if len(this.typeParams) > 0 {
-			fmt.Print("<", strings.Join(this.typeParams, ", "), ">")
+			fmt.Fprint(w, "<")
+			for i, tp := range this.typeParams {
+				if i > 0 {
+					fmt.Fprint(w, ", ")
+				}
+				fmt.Fprint(w, tp)
+			}
+			fmt.Fprint(w, ">")
 		}
 		if len(this.withs) > 0 {
-			fmt.Print(" with ")
-			for i, w := range this.withs {
+			fmt.Fprint(w, " with ")
+			for i, with := range this.withs {
 				if i > 0 {
-					fmt.Print(", ")
+					fmt.Fprint(w, ", ")
 				}
-				fmt.Print(w)
+				fmt.Fprint(w, with)
 			}
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 		for _, s := range this.statements {
-			Print(s, indent+1)
+			Fprint(w, s, indent+1)
 		}
 	case *Constructor:
 		this := obj.(*Constructor)
-		fmt.Println("cons")
-		Print(this.Type, indent+2)
-		printIndent(indent + 1)
-		fmt.Println("vals")
+		fmt.Fprintln(w, "cons")
+		Fprint(w, this.Type, indent+2)
+		printIndent(w, indent+1)
+		fmt.Fprintln(w, "vals")
 		for _, p := range this.params {
-			Print(p, indent+2)
+			Fprint(w, p, indent+2)
 		}
 	case *Defer:
-		fmt.Println("defer")
-		Print(obj.(*Defer).Expr, indent+1)
+		fmt.Fprintln(w, "defer")
+		Fprint(w, obj.(*Defer).Expr, indent+1)
 	case *Error:
-		fmt.Printf("ERROR: %v\n", obj.(*Error).Val)
+		fmt.Fprintf(w, "ERROR: %v\n", obj.(*Error).Val)
 	case *ExprList:
-		fmt.Println("expression list")
+		fmt.Fprintln(w, "expression list")
 		for _, e := range obj.(*ExprList).exprs {
-			Print(e, indent+1)
+			Fprint(w, e, indent+1)
 		}
 	case *ExprStmt:
-		fmt.Println("expr stmt")
-		Print(obj.(*ExprStmt).Expr, indent+1)
+		fmt.Fprintln(w, "expr stmt")
+		Fprint(w, obj.(*ExprStmt).Expr, indent+1)
 	case *File:
 		this := obj.(*File)
-		fmt.Println(this.Name)
+		fmt.Fprintln(w, this.Name)
 		for _, s := range this.statements {
-			Print(s, indent+1)
+			Fprint(w, s, indent+1)
 		}
 	case *For:
 		this := obj.(*For)
 		if len(this.Label) > 0 {
-			fmt.Print(this.Label, ": ")
+			fmt.Fprint(w, this.Label, ": ")
 		}
-		fmt.Println("for", strings.Join(this.vars, ", "), "in")
-		Print(this.In, indent+2)
+		fmt.Fprintln(w, "for", strings.Join(this.vars, ", "), "in")
+		Fprint(w, this.In, indent+2)
 		for _, s := range this.stmts {
-			Print(s, indent+1)
+			Fprint(w, s, indent+1)
 		}
 	case *FunctionCall:
 		this := obj.(*FunctionCall)
-		fmt.Println("func")
-		Print(this.Function, indent+2)
+		fmt.Fprintln(w, "func")
+		Fprint(w, this.Function, indent+2)
 		if this.Params != nil {
-			printIndent(indent + 1)
-			fmt.Println("params")
-			Print(this.Params, indent+2)
+			printIndent(w, indent+1)
+			fmt.Fprintln(w, "params")
+			Fprint(w, this.Params, indent+2)
 		}
 	case *FunctionDef:
 		this := obj.(*FunctionDef)
+		if this.Private {
+			fmt.Fprint(w, "priv ")
+		}
 		if this.Static {
-			fmt.Print("static ")
+			fmt.Fprint(w, "static ")
+		}
+		if this.Native {
+			fmt.Fprint(w, "native ")
 		}
 		if this.Name == "" {
-			fmt.Print("fn")
+			fmt.Fprint(w, "fn")
 		} else {
-			fmt.Print(this.Name)
+			fmt.Fprint(w, this.Name)
 		}
-		fmt.Print("(")
+		fmt.Fprint(w, "(")
 		for i, p := range this.params {
 			if i > 0 {
-				fmt.Print(", ")
+				fmt.Fprint(w, ", ")
 			}
-			fmt.Print(p)
+			fmt.Fprint(w, p)
 		}
-		fmt.Print(")")
+		fmt.Fprint(w, ")")
 		if len(this.returns) > 0 {
-			fmt.Print(" ")
+			fmt.Fprint(w, " ")
 			if len(this.returns) > 1 {
-				fmt.Print("(")
+				fmt.Fprint(w, "(")
 			}
 			for i, r := range this.returns {
 				if i > 0 {
-					fmt.Print(", ")
+					fmt.Fprint(w, ", ")
 				}
-				fmt.Print(r)
+				fmt.Fprint(w, r)
 			}
 			if len(this.returns) > 1 {
-				fmt.Print(")")
+				fmt.Fprint(w, ")")
 			}
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 		for _, s := range this.statements {
-			Print(s, indent+1)
+			Fprint(w, s, indent+1)
 		}
 	case *FunctionSig:
-		fmt.Println(obj)
+		fmt.Fprintln(w, obj)
 	case *Identifier:
 		for i, id := range obj.(*Identifier).idents {
 			if i > 0 {
-				fmt.Print(".")
+				fmt.Fprint(w, ".")
 			}
-			fmt.Print(id)
+			fmt.Fprint(w, id)
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 	case *If:
 		this := obj.(*If)
-		fmt.Println("if")
-		Print(this.Condition, indent+1)
+		fmt.Fprintln(w, "if")
+		Fprint(w, this.Condition, indent+1)
 		if this.With != nil {
-			printIndent(indent + 1)
-			fmt.Println("with")
-			Print(this.With, indent+2)
+			printIndent(w, indent+1)
+			fmt.Fprintln(w, "with")
+			Fprint(w, this.With, indent+2)
 		}
-		printIndent(indent + 1)
-		fmt.Println("then")
+		printIndent(w, indent+1)
+		fmt.Fprintln(w, "then")
 		for _, s := range this.stmts {
-			Print(s, indent+2)
+			Fprint(w, s, indent+2)
 		}
 	case *Interface:
 		this := obj.(*Interface)
-		fmt.Print("interface ", this.Name)
+		if this.Private {
+			fmt.Fprint(w, "priv ")
+		}
+		fmt.Fprint(w, "interface ", this.Name)
 		if len(this.withs) > 0 {
-			fmt.Print(" with ")
-			for i, w := range this.withs {
+			fmt.Fprint(w, " with ")
+			for i, with := range this.withs {
 				if i > 0 {
-					fmt.Print(", ")
+					fmt.Fprint(w, ", ")
 				}
-				fmt.Print(w)
+				fmt.Fprint(w, with)
 			}
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 		for _, f := range this.funcSigs {
-			Print(f, indent+1)
+			Fprint(w, f, indent+1)
 		}
 	case *IntfFuncSig:
-		fmt.Println(obj.(*IntfFuncSig))
+		fmt.Fprintln(w, obj.(*IntfFuncSig))
 	case *Iota:
-		fmt.Println("iota")
+		fmt.Fprintln(w, "iota")
 	case *Is:
 		this := obj.(*Is)
-		fmt.Println("is")
-		Print(this.Condition, indent+1)
-		printIndent(indent + 1)
-		fmt.Println("then")
+		fmt.Fprintln(w, "is")
+		Fprint(w, this.Condition, indent+1)
+		printIndent(w, indent+1)
+		fmt.Fprintln(w, "then")
 		for _, s := range this.stmts {
-			Print(s, indent+2)
+			Fprint(w, s, indent+2)
 		}
 	case *KeyVal:
 		this := obj.(*KeyVal)
-		fmt.Printf("%v:\n", this.Key)
-		Print(this.Val, indent+1)
+		fmt.Fprintf(w, "%v:\n", this.Key)
+		Fprint(w, this.Val, indent+1)
 	case *Loop:
 		this := obj.(*Loop)
 		if len(this.Label) > 0 {
-			fmt.Print(this.Label, ": ")
+			fmt.Fprint(w, this.Label, ": ")
 		}
-		fmt.Println("loop")
+		fmt.Fprintln(w, "loop")
 		for _, s := range this.stmts {
-			Print(s, indent+1)
+			Fprint(w, s, indent+1)
 		}
+	case *MapEntry:
+		this := obj.(*MapEntry)
+		fmt.Fprintln(w, "map entry")
+		Fprint(w, this.Key, indent+1)
+		Fprint(w, this.Val, indent+1)
+	case *MapValueList:
+		fmt.Fprintln(w, "map val list")
+		Fprint(w, obj.(*MapValueList).Vals, indent+1)
+	case *Nil:
+		fmt.Fprintln(w, "nil")
 	case *Number:
-		fmt.Println("number", obj.(*Number).Val)
+		fmt.Fprintln(w, "number", obj.(*Number).Val)
+	case *Optional:
+		fmt.Fprintln(w, obj)
+	case *Package:
+		this := obj.(*Package)
+		fmt.Fprintln(w, "package", this.Name)
+		for _, f := range this.files {
+			Fprint(w, f, indent+1)
+		}
 	case *PropertySet:
 		this := obj.(*PropertySet)
-		fmt.Print("prop set: ")
+		fmt.Fprint(w, "prop set: ")
+		if this.Private {
+			fmt.Fprint(w, "priv ")
+		}
 		for i, p := range this.props {
 			if i > 0 {
-				fmt.Print(", ")
+				fmt.Fprint(w, ", ")
 			}
-			fmt.Print(p)
+			fmt.Fprint(w, p)
 		}
-		fmt.Println()
-		Print(this.Vals, indent+1)
+		fmt.Fprintln(w)
+		Fprint(w, this.Vals, indent+1)
+	case *Propagate:
+		fmt.Fprintln(w, "propagate ?")
+		Fprint(w, obj.(*Propagate).Expr, indent+1)
+	case *RangePattern:
+		this := obj.(*RangePattern)
+		fmt.Fprintln(w, "range pattern")
+		printIndent(w, indent+1)
+		fmt.Fprintln(w, "from")
+		Fprint(w, this.Low, indent+2)
+		printIndent(w, indent+1)
+		fmt.Fprintln(w, "to")
+		Fprint(w, this.High, indent+2)
+	case *Receive:
+		fmt.Fprintln(w, "receive")
+		Fprint(w, obj.(*Receive).Chan, indent+1)
 	case *Return:
-		fmt.Println("ret")
-		Print(obj.(*Return).Vals, indent+1)
+		fmt.Fprintln(w, "ret")
+		Fprint(w, obj.(*Return).Vals, indent+1)
+	case *Select:
+		fmt.Fprintln(w, "select")
+		for _, s := range obj.(*Select).stmts {
+			Fprint(w, s, indent+1)
+		}
+	case *SelectCase:
+		this := obj.(*SelectCase)
+		fmt.Fprintln(w, "select case")
+		if this.Comm == nil {
+			printIndent(w, indent+1)
+			fmt.Fprintln(w, "_")
+		} else {
+			Fprint(w, this.Comm, indent+1)
+		}
+		printIndent(w, indent+1)
+		fmt.Fprintln(w, "then")
+		for _, s := range this.stmts {
+			Fprint(w, s, indent+2)
+		}
+	case *Send:
+		this := obj.(*Send)
+		fmt.Fprintln(w, "send")
+		Fprint(w, this.Chan, indent+1)
+		Fprint(w, this.Val, indent+1)
+	case *ShapePattern:
+		this := obj.(*ShapePattern)
+		fmt.Fprintln(w, "shape pattern", this.Type)
+		for _, f := range this.fields {
+			if f.Val == nil {
+				printIndent(w, indent+1)
+				fmt.Fprintln(w, f.Key)
+			} else {
+				Fprint(w, f, indent+1)
+			}
+		}
+	case *Spawn:
+		fmt.Fprintln(w, "spawn")
+		Fprint(w, obj.(*Spawn).Expr, indent+1)
 	case *String:
-		fmt.Printf("string '%v'\n", obj.(*String).Val)
+		fmt.Fprintf(w, "string '%v'\n", obj.(*String).Val)
+	case *TypePattern:
+		this := obj.(*TypePattern)
+		fmt.Fprintln(w, "type pattern", this.Type, this.Name)
 	case *Unary:
 		this := obj.(*Unary)
-		fmt.Println(this.Op)
-		Print(this.Expr, indent+1)
+		fmt.Fprintln(w, this.Op)
+		Fprint(w, this.Expr, indent+1)
 	case *Use:
 		this := obj.(*Use)
-		fmt.Println("use")
+		fmt.Fprintln(w, "use")
 		for _, p := range this.packages {
-			printIndent(indent + 1)
-			fmt.Println(p)
+			printIndent(w, indent+1)
+			fmt.Fprintln(w, p)
 		}
 	case *VarSet:
-		fmt.Println("var set")
+		fmt.Fprintln(w, "var set")
 		for _, l := range obj.(*VarSet).lines {
-			Print(l, indent+1)
+			Fprint(w, l, indent+1)
 		}
 	case *VarSetLine:
 		this := obj.(*VarSetLine)
 		for i, v := range this.vars {
 			if i > 0 {
-				fmt.Print(", ")
+				fmt.Fprint(w, ", ")
 			}
-			fmt.Print(v)
+			fmt.Fprint(w, v)
 		}
-		fmt.Println()
-		Print(this.Vals, indent+1)
+		fmt.Fprintln(w)
+		Fprint(w, this.Vals, indent+1)
 	default:
-		fmt.Println("Unknown type %T", t)
+		fmt.Fprintf(w, "Unknown type %T\n", t)
 	}
 }
@@ -0,0 +1,149 @@
+package ast
+
+// Inspect traverses the tree rooted at obj in depth-first order, calling f for
+// every node. If f returns false the children of that node are skipped. Types
+// are visited along with statements and expressions.
+func Inspect(obj General, f func(General) bool) {
+	if obj == nil || !f(obj) {
+		return
+	}
+	switch this := obj.(type) {
+	case *Accessor:
+		inspectAll(f, this.Object, this.Index)
+	case *AccessorRange:
+		inspectAll(f, this.Object, this.Low, this.High)
+	case *Alias:
+		Inspect(this.Val, f)
+	case *ArrayCons:
+		inspectAll(f, this.Type, this.Size)
+	case *Array:
+		Inspect(this.Type, f)
+	case *ArrayValueList:
+		Inspect(this.Vals, f)
+	case *Assign:
+		inspectAll(f, this.Left, this.Right)
+	case *Binary:
+		inspectAll(f, this.Left, this.Right)
+	case *Class:
+		for _, tp := range this.typeParams {
+			Inspect(tp.bound, f)
+		}
+		inspectStmts(f, this.withs)
+		inspectStmts(f, this.statements)
+	case *Constructor:
+		Inspect(this.Type, f)
+		inspectStmts(f, this.params)
+	case *Defer:
+		Inspect(this.Expr, f)
+	case *ExprList:
+		for _, e := range this.exprs {
+			Inspect(e, f)
+		}
+	case *ExprStmt:
+		Inspect(this.Expr, f)
+	case *File:
+		inspectStmts(f, this.statements)
+	case *For:
+		Inspect(this.In, f)
+		inspectStmts(f, this.stmts)
+	case *FunctionCall:
+		inspectAll(f, this.Function, this.Params)
+	case *FunctionDef:
+		for _, p := range this.params {
+			Inspect(p.typ, f)
+		}
+		inspectStmts(f, this.returns)
+		inspectStmts(f, this.statements)
+	case *FunctionSig:
+		inspectStmts(f, this.params)
+		inspectStmts(f, this.returns)
+	case *Identifier:
+		for _, id := range this.idents {
+			Inspect(id, f)
+		}
+	case *IdentPart:
+		inspectStmts(f, this.typeParams)
+	case *If:
+		inspectAll(f, this.Condition, this.With)
+		inspectStmts(f, this.stmts)
+	case *Interface:
+		inspectStmts(f, this.withs)
+		inspectStmts(f, this.funcSigs)
+	case *IntfFuncSig:
+		inspectStmts(f, this.params)
+		inspectStmts(f, this.returns)
+	case *Is:
+		Inspect(this.Condition, f)
+		inspectStmts(f, this.stmts)
+	case *KeyVal:
+		Inspect(this.Val, f)
+	case *Loop:
+		inspectStmts(f, this.stmts)
+	case *MapEntry:
+		inspectAll(f, this.Key, this.Val)
+	case *MapValueList:
+		Inspect(this.Vals, f)
+	case *Optional:
+		Inspect(this.Type, f)
+	case *Package:
+		for _, file := range this.files {
+			Inspect(file, f)
+		}
+	case *PropertySet:
+		for _, p := range this.props {
+			Inspect(p.typ, f)
+		}
+		Inspect(this.Vals, f)
+	case *Propagate:
+		Inspect(this.Expr, f)
+	case *RangePattern:
+		inspectAll(f, this.Low, this.High)
+	case *Receive:
+		Inspect(this.Chan, f)
+	case *Return:
+		Inspect(this.Vals, f)
+	case *Select:
+		inspectStmts(f, this.stmts)
+	case *SelectCase:
+		Inspect(this.Comm, f)
+		inspectStmts(f, this.stmts)
+	case *Send:
+		inspectAll(f, this.Chan, this.Val)
+	case *ShapePattern:
+		Inspect(this.Type, f)
+		for _, kv := range this.fields {
+			Inspect(kv, f)
+		}
+	case *Spawn:
+		Inspect(this.Expr, f)
+	case *TypeIdent:
+		inspectStmts(f, this.typeParams)
+	case *TypePattern:
+		Inspect(this.Type, f)
+	case *Unary:
+		Inspect(this.Expr, f)
+	case *VarSet:
+		for _, l := range this.lines {
+			Inspect(l, f)
+		}
+	case *VarSetLine:
+		for _, v := range this.vars {
+			Inspect(v.typ, f)
+		}
+		Inspect(this.Vals, f)
+	}
+}
+
+// inspectAll inspects each of objs in order. A nil Statement or Expression
+// becomes a nil General when passed in, so it is skipped by Inspect.
+func inspectAll(f func(General) bool, objs ...General) {
+	for _, obj := range objs {
+		Inspect(obj, f)
+	}
+}
+
+func inspectStmts(f func(General) bool, stmts []Statement) {
+	for _, s := range stmts {
+		Inspect(s, f)
+	}
+}
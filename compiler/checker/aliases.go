@@ -0,0 +1,83 @@
+package checker
+
+import (
+	"github.com/defiant00/char/compiler/ast"
+	"strings"
+)
+
+// checkAliases resolves every alias in the package to the type it stands
+// for, following aliases of aliases, and reports alias cycles. Aliases are
+// declared package-wide, so they can be used before they're declared.
+func (this *checker) checkAliases() {
+	for _, f := range this.pkg.Files() {
+		for _, s := range f.Stmts() {
+			if a, ok := s.(*ast.Alias); ok {
+				this.resolveAlias(a, nil)
+			}
+		}
+	}
+}
+
+// resolveAlias returns the type a stands for with every alias in it
+// resolved, or nil if a is part of a cycle, resolving it first if needed.
+// Path holds the aliases currently being resolved, in order, for reporting
+// cycles.
+func (this *checker) resolveAlias(a *ast.Alias, path []*ast.Alias) ast.Statement {
+	switch this.aliasing[a] {
+	case flattening:
+		// Listed backwards so each step reads like its declaration.
+		names := []string{a.Alias}
+		for i := len(path) - 1; i >= 0; i-- {
+			names = append(names, path[i].Alias)
+			if path[i] == a {
+				break
+			}
+		}
+		this.errorf(this.fileOf[a], a.Pos, "alias cycle: %v", strings.Join(names, " as "))
+		return nil
+	case flattened:
+		return this.info.Aliases[a]
+	}
+	this.aliasing[a] = flattening
+	path = append(path, a)
+
+	cyclic := false
+	ast.Inspect(a.Val, func(n ast.General) bool {
+		if t, ok := n.(*ast.TypeIdent); ok {
+			if used := this.aliasNamed(t); used != nil && this.resolveAlias(used, path) == nil {
+				cyclic = true
+			}
+		}
+		return true
+	})
+	this.aliasing[a] = flattened
+	if cyclic {
+		return nil // an alias in a cycle stands for nothing
+	}
+
+	typ := this.resolveType(a.Val)
+	this.info.Aliases[a] = typ
+	this.aliases[a.Alias] = typ
+	return typ
+}
+
+// aliasNamed returns the alias t names, if any.
+func (this *checker) aliasNamed(t *ast.TypeIdent) *ast.Alias {
+	if len(t.Idents()) != 1 || len(t.TypeParams()) != 0 {
+		return nil
+	}
+	if obj := this.pkg.Scope.Lookup(t.Idents()[0]); obj != nil {
+		a, _ := obj.Decl.(*ast.Alias)
+		return a
+	}
+	return nil
+}
+
+// resolveType returns typ with every resolved alias in it replaced by the
+// type it stands for.
+func (this *checker) resolveType(typ ast.Statement) ast.Statement {
+	if len(this.aliases) == 0 {
+		return typ
+	}
+	return substType(typ, this.aliases)
+}
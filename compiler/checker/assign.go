@@ -0,0 +1,154 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/token"
+	"strings"
+)
+
+// checkAssigns checks that every assignment, var line and member property
+// set has as many values as targets. A single call to a function with several
+// results provides one value per result; anywhere else a call must provide
+// exactly one value. A single receive can also provide whether its channel is
+// still open. Blanks can only be assigned to.
+func (this *checker) checkAssigns() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			switch s := s.(type) {
+			case *ast.FunctionDef:
+				env := &constEnv{file: f, class: c, locals: localNames(s)}
+				ast.Inspect(s, func(n ast.General) bool {
+					switch n := n.(type) {
+					case *ast.Assign:
+						this.checkAssign(env, n)
+					case *ast.VarSetLine:
+						if n.Vals != nil {
+							this.checkValues(env, n.Pos, len(n.Vars()), "variables", n.Vals)
+						}
+					}
+					return true
+				})
+			case *ast.PropertySet:
+				if !isConstSet(s) && s.Vals != nil {
+					this.checkValues(&constEnv{file: f, class: c}, s.Pos, len(s.Props()), "properties", s.Vals)
+				}
+			}
+		}
+	})
+}
+
+func (this *checker) checkAssign(env *constEnv, a *ast.Assign) {
+	targets := exprsOf(a.Left)
+	if a.Op != token.ASSIGN {
+		if len(targets) != 1 || len(exprsOf(a.Right)) != 1 {
+			this.errorf(env.file, a.Pos, "assignment operation %v requires single-valued expressions", a.Op)
+			return
+		}
+		if _, ok := targets[0].(*ast.Blank); ok {
+			this.errorf(env.file, a.Pos, "cannot use _ as value")
+			return
+		}
+	}
+	this.checkValues(env, a.Pos, len(targets), "variables", a.Right)
+}
+
+// checkValues checks that vals provides n values for the targets, which are
+// described by what.
+func (this *checker) checkValues(env *constEnv, pos token.Position, n int, what string, vals ast.Expression) {
+	exprs := exprsOf(vals)
+	for _, e := range exprs {
+		ast.Inspect(e, func(n ast.General) bool {
+			if b, ok := n.(*ast.Blank); ok {
+				this.errorf(env.file, b.Pos, "cannot use _ as value")
+			}
+			return true
+		})
+	}
+
+	if len(exprs) == 1 && n == 2 && isReceive(exprs[0]) {
+		return // the value and whether the channel is still open
+	}
+	if len(exprs) == 1 && n > 1 && isCall(exprs[0]) {
+		// Functions from elsewhere are trusted to return enough values.
+		if name, sig := this.calledFunc(env, exprs[0]); sig != nil && len(sig.Returns()) != n {
+			this.errorf(env.file, pos, "assignment mismatch: %v %v but %v returns %v", n, what, name, plural(len(sig.Returns()), "value"))
+		}
+		return
+	}
+	for _, e := range exprs {
+		name, sig := this.calledFunc(env, e)
+		switch {
+		case sig == nil:
+		case len(sig.Returns()) == 0:
+			this.errorf(env.file, ast.PosOf(e), "%v() (no value) used as value", name)
+		case len(sig.Returns()) > 1:
+			this.errorf(env.file, ast.PosOf(e), "multiple-value %v() (%v) in single-value context", name, plural(len(sig.Returns()), "value"))
+		}
+	}
+	if len(exprs) != n {
+		this.errorf(env.file, pos, "assignment mismatch: %v %v but %v", n, what, plural(len(exprs), "value"))
+	}
+}
+
+// calledFunc returns the name and signature of the function e calls, if e is
+// a call to a function of the package, or nil. The signature of a call with ?
+// leaves out the Error result.
+func (this *checker) calledFunc(env *constEnv, e ast.Expression) (string, *ast.IntfFuncSig) {
+	if p, ok := e.(*ast.Propagate); ok {
+		return this.propagated(env, p)
+	}
+	call, ok := e.(*ast.FunctionCall)
+	if !ok {
+		return "", nil
+	}
+	id, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return "", nil
+	}
+	names := identNames(id.Idents())
+	if env.locals[names[0]] {
+		return "", nil
+	}
+	last := len(names) - 1
+	var m *Member
+	if last == 0 {
+		m = this.info.Member(env.class, names[0])
+	} else if c := this.lookupClass(names[:last]...); c != nil {
+		if m = this.info.Member(c, names[last]); m != nil && !m.Static {
+			m = nil // an instance method needs an instance
+		}
+	}
+	if m == nil && (last == 0 || last == 1 && this.isPreludeName(names[0])) {
+		m = this.builtin(names[last])
+	}
+	if m == nil || m.Sig == nil {
+		return "", nil
+	}
+	return strings.Join(names, "."), m.Sig
+}
+
+// exprsOf returns the expressions of a list, or e alone.
+func exprsOf(e ast.Expression) []ast.Expression {
+	if el, ok := e.(*ast.ExprList); ok {
+		return el.Exprs()
+	}
+	return []ast.Expression{e}
+}
+
+// isCall returns whether e is a call, with or without ?.
+func isCall(e ast.Expression) bool {
+	if p, ok := e.(*ast.Propagate); ok {
+		e = p.Expr
+	}
+	_, ok := e.(*ast.FunctionCall)
+	return ok
+}
+
+// plural returns n followed by word, made plural unless n is 1.
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%v %v", n, word)
+	}
+	return fmt.Sprintf("%v %vs", n, word)
+}
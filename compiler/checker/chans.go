@@ -0,0 +1,115 @@
+package checker
+
+import (
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/token"
+)
+
+// chanClass is the prelude class that channel types instantiate.
+const chanClass = "chan"
+
+// checkChans checks every spawn, send, receive and select. A spawn runs a
+// call, sends and receives need a channel of a matching type where it's
+// known, and every case of a select but a single is _ sends or receives.
+func (this *checker) checkChans() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			if fn, ok := s.(*ast.FunctionDef); ok {
+				env := &typeEnv{constEnv: constEnv{file: f, class: c, locals: localNames(fn)}, vars: localTypes(fn)}
+				ast.Inspect(fn, func(n ast.General) bool {
+					switch n := n.(type) {
+					case *ast.Spawn:
+						if _, ok := n.Expr.(*ast.FunctionCall); !ok {
+							this.errorf(f, n.Pos, "spawn needs a function call")
+						}
+					case *ast.Send:
+						if elem, ok := this.chanOf(env, n.Chan, "send to"); ok {
+							this.checkElem(env, n.Val, elem, "send")
+						}
+					case *ast.Receive:
+						this.chanOf(env, n.Chan, "receive from")
+					case *ast.Select:
+						this.checkSelect(env, n)
+					}
+					return true
+				})
+			}
+		}
+	})
+}
+
+// chanOf returns the type of the values of the channel e, reporting an error
+// that it can't be used to do as e isn't a channel. It returns false if e's
+// type isn't known.
+func (this *checker) chanOf(env *typeEnv, e ast.Expression, do string) (ast.Statement, bool) {
+	typ := this.typeOf(env, e)
+	if typ == nil {
+		return nil, false
+	}
+	if elem := this.chanElem(typ); elem != nil {
+		return elem, true
+	}
+	if this.basicTypeName(typ) != "" || this.typeDecl(typ) != nil {
+		this.errorf(env.file, ast.PosOf(e), "cannot %v %v, it isn't a chan", do, typ)
+	}
+	return nil, false
+}
+
+// chanElem returns the type of the values of typ if it is a channel, or nil.
+func (this *checker) chanElem(typ ast.Statement) ast.Statement {
+	if args := this.preludeTypeArgs(typ, chanClass, 1); args != nil {
+		return args[0]
+	}
+	return nil
+}
+
+func (this *checker) checkSelect(env *typeEnv, s *ast.Select) {
+	seenDefault := false
+	for _, st := range s.Stmts() {
+		sc, ok := st.(*ast.SelectCase)
+		if !ok {
+			continue
+		}
+		switch {
+		case sc.Comm == nil && seenDefault:
+			this.errorf(env.file, sc.Pos, "more than one is _ in select")
+		case sc.Comm == nil:
+			seenDefault = true
+		case !communicates(sc.Comm):
+			this.errorf(env.file, sc.Pos, "select case must send or receive")
+		}
+	}
+}
+
+// communicates returns whether s is a send, or receives a single value that
+// is then either discarded or assigned.
+func communicates(s ast.Statement) bool {
+	var vals ast.Expression
+	switch s := s.(type) {
+	case *ast.Send:
+		return true
+	case *ast.ExprStmt:
+		vals = s.Expr
+	case *ast.Assign:
+		if s.Op != token.ASSIGN {
+			return false
+		}
+		vals = s.Right
+	case *ast.VarSet:
+		lines := s.Lines()
+		if len(lines) != 1 {
+			return false
+		}
+		vals = lines[0].Vals
+	}
+	if vals == nil {
+		return false
+	}
+	exprs := exprsOf(vals)
+	return len(exprs) == 1 && isReceive(exprs[0])
+}
+
+func isReceive(e ast.Expression) bool {
+	_, ok := e.(*ast.Receive)
+	return ok
+}
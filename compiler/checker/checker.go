@@ -0,0 +1,232 @@
+// Package checker performs the semantic checks on a parsed package and
+// records what it learns in an Info for the code generators.
+package checker
+
+import (
+	"fmt"
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/constant"
+	"github.com/defiant00/char/compiler/prelude"
+	"github.com/defiant00/char/compiler/token"
+)
+
+// Info holds the results of checking a package.
+type Info struct {
+	// Consts holds the constants of every class in declaration order.
+	Consts map[*ast.Class][]*Const
+
+	// Values holds the folded value of every constant expression outside of
+	// class constant declarations, including each constant part of a larger
+	// expression.
+	Values map[ast.Expression]constant.Value
+
+	// Members holds the members of every class with its mixins flattened
+	// into it.
+	Members map[*ast.Class][]*Member
+
+	// Methods holds the methods of every interface, including those of the
+	// interfaces it embeds.
+	Methods map[*ast.Interface][]*ast.IntfFuncSig
+
+	// Aliases holds the type every alias stands for, with the aliases in it
+	// resolved in turn. Aliases in a cycle are left out.
+	Aliases map[*ast.Alias]ast.Statement
+
+	// Binds holds the variables bound by the patterns of every is case that
+	// binds any, in order.
+	Binds map[*ast.Is][]string
+
+	// Flows holds the control-flow graph of every function.
+	Flows map[*ast.FunctionDef]*Flow
+
+	// Fors holds how every for loop iterates.
+	Fors map[*ast.For]*ForLoop
+
+	// Captures holds the locals that every anonymous function captures, in
+	// the order they are first used.
+	Captures map[*ast.FunctionDef][]*Capture
+
+	// Operators holds the method that every binary, unary and assignment
+	// operation on a class instance calls.
+	Operators map[ast.General]*Operator
+
+	// Instances holds every instantiation of a generic class, in the order
+	// they were found.
+	Instances []*Instance
+}
+
+// Const returns the constant name of class c, or nil if there isn't one.
+func (this *Info) Const(c *ast.Class, name string) *Const {
+	for _, k := range this.Consts[c] {
+		if k.Name == name {
+			return k
+		}
+	}
+	return nil
+}
+
+// Member returns the member name of class c, or nil if there isn't one.
+func (this *Info) Member(c *ast.Class, name string) *Member {
+	for _, m := range this.Members[c] {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Instance returns the instantiation of a generic class written as typ,
+// e.g. "Pair<int, string>", or nil if there isn't one.
+func (this *Info) Instance(typ string) *Instance {
+	for _, inst := range this.Instances {
+		if inst.Type.String() == typ {
+			return inst
+		}
+	}
+	return nil
+}
+
+// Error is a problem found in a file.
+type Error struct {
+	File string
+	Pos  token.Position
+	Msg  string
+}
+
+func (this *Error) Error() string {
+	return fmt.Sprintf("%v:%v: %v", this.File, this.Pos, this.Msg)
+}
+
+type checker struct {
+	pkg      *ast.Package
+	info     *Info
+	errs     []error
+	fileOf   map[ast.Statement]*ast.File // the file of every top-level statement
+	aliasing map[*ast.Alias]mixState
+	aliases  map[string]ast.Statement // alias name to resolved type
+	consts   map[*ast.Class]map[string]*constDecl
+	mixing   map[*ast.Class]mixState
+	intfs    map[*ast.Interface]mixState
+	insts    map[string]*Instance
+	uses     map[string]string // package name to path, from use statements
+	shared   map[string]bool   // locals of the function being checked that a closure assigns
+}
+
+// Check checks pkg, returning what it found along with every error. The
+// prelude's declarations are in scope in every package but the prelude itself.
+func Check(pkg *ast.Package) (*Info, []error) {
+	c := newChecker(pkg)
+	if pkg.Name != prelude.Name {
+		c.usePrelude()
+	}
+	c.check()
+	return c.info, c.errs
+}
+
+func newChecker(pkg *ast.Package) *checker {
+	c := &checker{
+		pkg: pkg,
+		info: &Info{
+			Consts:    make(map[*ast.Class][]*Const),
+			Values:    make(map[ast.Expression]constant.Value),
+			Members:   make(map[*ast.Class][]*Member),
+			Methods:   make(map[*ast.Interface][]*ast.IntfFuncSig),
+			Aliases:   make(map[*ast.Alias]ast.Statement),
+			Binds:     make(map[*ast.Is][]string),
+			Flows:     make(map[*ast.FunctionDef]*Flow),
+			Fors:      make(map[*ast.For]*ForLoop),
+			Captures:  make(map[*ast.FunctionDef][]*Capture),
+			Operators: make(map[ast.General]*Operator),
+		},
+		fileOf:   make(map[ast.Statement]*ast.File),
+		aliasing: make(map[*ast.Alias]mixState),
+		aliases:  make(map[string]ast.Statement),
+		consts:   make(map[*ast.Class]map[string]*constDecl),
+		mixing:   make(map[*ast.Class]mixState),
+		intfs:    make(map[*ast.Interface]mixState),
+		insts:    make(map[string]*Instance),
+		uses:     make(map[string]string),
+	}
+	for _, f := range pkg.Files() {
+		for _, s := range f.Stmts() {
+			c.fileOf[s] = f
+			if u, ok := s.(*ast.Use); ok {
+				for _, p := range u.Packages() {
+					c.uses[p.Name()] = p.Package()
+				}
+			}
+		}
+	}
+	return c
+}
+
+func (this *checker) check() {
+	this.checkAliases()
+	this.checkConsts()
+	this.checkEnums()
+	this.flattenMixins()
+	this.checkInterfaces()
+	this.checkGenerics()
+	this.foldFuncs()
+	this.checkPatterns()
+	this.checkMatches()
+	this.checkAssigns()
+	this.checkPropagation()
+	this.checkMaps()
+	this.checkConversions()
+	this.checkChans()
+	this.checkOperators()
+	this.checkClosures()
+	this.checkOptionals()
+	this.checkFlow()
+	this.checkLoops()
+	this.checkNatives()
+	this.checkVisibility()
+}
+
+func (this *checker) errorf(file *ast.File, pos token.Position, format string, args ...interface{}) {
+	this.errs = append(this.errs, &Error{File: file.Name, Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// classes calls fn for every class and mixin in the package.
+func (this *checker) classes(fn func(f *ast.File, c *ast.Class)) {
+	for _, f := range this.pkg.Files() {
+		for _, s := range f.Stmts() {
+			if c, ok := s.(*ast.Class); ok {
+				fn(f, c)
+			}
+		}
+	}
+}
+
+// lookupClass returns the class or mixin declared as names in the package,
+// or in the prelude if qualified by it.
+func (this *checker) lookupClass(names ...string) *ast.Class {
+	if obj, rest := this.lookupName(names); obj != nil && len(rest) == 0 {
+		if c, ok := obj.Decl.(*ast.Class); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// lookupName returns the declaration the first of names refers to and the
+// names after it. Names qualified by the prelude, such as prelude.Range, are
+// looked up in the prelude alone. Use statements are visible throughout the
+// package.
+func (this *checker) lookupName(names []string) (*ast.Object, []string) {
+	scope := this.pkg.Scope
+	if len(names) > 1 && this.isPreludeName(names[0]) {
+		scope, names = this.preludeScope(), names[1:]
+	}
+	return scope.Lookup(names[0]), names[1:]
+}
+
+// identNames returns the names of the parts of an identifier.
+func identNames(parts []*ast.IdentPart) []string {
+	names := make([]string, 0, len(parts))
+	for _, p := range parts {
+		names = append(names, p.Name)
+	}
+	return names
+}
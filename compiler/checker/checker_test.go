@@ -0,0 +1,1137 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/lexer"
+	"github.com/defiant00/char/compiler/parser"
+	"strings"
+	"testing"
+)
+
+// check parses src as the only file of a package and checks it.
+func check(t *testing.T, src string) (*ast.Package, *Info, []error) {
+	t.Helper()
+	fAST, _ := parser.ParseTokens("test.char", lexer.LexAll(src))
+	if errs := ast.ParseErrors("test.char", fAST); len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	f, ok := fAST.(*ast.File)
+	if !ok {
+		t.Fatalf("parsing failed: %v", fAST)
+	}
+	pkg, errs := ast.NewPackage("test", []*ast.File{f})
+	if len(errs) > 0 {
+		t.Fatalf("unexpected package errors: %v", errs)
+	}
+	info, errs := Check(pkg)
+	return pkg, info, errs
+}
+
+// checkErrors checks that errs holds exactly one error per entry in want,
+// each containing the matching text.
+func checkErrors(t *testing.T, errs []error, want ...string) {
+	t.Helper()
+	if len(errs) != len(want) {
+		t.Fatalf("got %v errors %v, want %v", len(errs), errs, len(want))
+	}
+	for i, w := range want {
+		if !strings.Contains(errs[i].Error(), w) {
+			t.Errorf("error %v is %q, want it to contain %q", i, errs[i], w)
+		}
+	}
+}
+
+func class(pkg *ast.Package, name string) *ast.Class {
+	return pkg.Scope.Lookup(name).Decl.(*ast.Class)
+}
+
+func TestConsts(t *testing.T) {
+	pkg, info, errs := check(t, `Flags
+	greeting = "Hello"
+	First = iota
+	Second
+	Third
+
+	iota
+	Read = 1 << iota
+	Write
+	Exec
+	All = Read | Write | Exec
+
+	iota
+	A, B = iota * 10, -iota
+	C, D
+	E = Other.Max - 1
+
+Other
+	Max = 1 << 100
+`)
+	checkErrors(t, errs)
+
+	want := map[string]string{
+		"greeting": `"Hello"`,
+		"First":    "1",
+		"Second":   "2",
+		"Third":    "3",
+		"Read":     "1",
+		"Write":    "2",
+		"Exec":     "4",
+		"All":      "7",
+		"A":        "0",
+		"B":        "0",
+		"C":        "10",
+		"D":        "-1",
+		"E":        "1267650600228229401496703205375",
+	}
+	c := class(pkg, "Flags")
+	if len(info.Consts[c]) != len(want) {
+		t.Fatalf("got %v constants, want %v", len(info.Consts[c]), len(want))
+	}
+	for name, val := range want {
+		if k := info.Const(c, name); k == nil {
+			t.Errorf("constant %v is missing", name)
+		} else if k.Value.String() != val {
+			t.Errorf("%v = %v, want %v", name, k.Value, val)
+		}
+	}
+}
+
+func TestConstErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"C\n\tA\n", []string{"missing value for constant A"}},
+		{"C\n\tA, B = 1\n", []string{"2 constants but 1 values"}},
+		{"C\n\tA = 1\n\tA = 2\n", []string{"constant A redeclared"}},
+		{"C\n\tA = f()\n", []string{"value of constant A cannot be computed"}},
+		{"C\n\tA = B\n\tB = A\n", []string{"initialization cycle"}},
+		{"C\n\tA = 1 / (iota - iota)\n", []string{"division by zero"}},
+		{"C\n\tA = 1 << -1\n", []string{"invalid shift count"}},
+		{"C\n\tA = \"a\" + 1\n", []string{"mismatched kinds"}},
+		{"C\n\tA, .b = 1, 2\n", []string{"constants and members together"}},
+	}
+	for _, test := range tests {
+		_, _, errs := check(t, test.src)
+		checkErrors(t, errs, test.want...)
+	}
+}
+
+func TestTypedConsts(t *testing.T) {
+	pkg, info, errs := check(t, `C
+	Half = 1 / 2.0
+	Ratio float32 = 3 / 4.0
+	Whole int = 10 / 2.0
+	Newline char = '\n'
+	Greeting = "Hi\tthere"
+	Max uint8 = 1 << 8 - 1
+	Big int8 = 128
+	Small int8 = -129
+	Min int8 = -128
+	Cut int = 1.5
+	Wrong string = 1
+	Odd C = 1
+`)
+	checkErrors(t, errs,
+		"constant 128 overflows int8",
+		"constant -129 overflows int8",
+		"constant 1.5 truncated to int",
+		"cannot use 1 as string",
+		"invalid constant type C")
+
+	want := map[string]string{
+		"Half":     "0.5",
+		"Ratio":    "0.75",
+		"Whole":    "5",
+		"Newline":  "10",
+		"Greeting": `"Hi\tthere"`,
+		"Max":      "255",
+		"Min":      "-128",
+	}
+	c := class(pkg, "C")
+	for name, val := range want {
+		if k := info.Const(c, name); k.Value.String() != val {
+			t.Errorf("%v = %v, want %v", name, k.Value, val)
+		}
+	}
+	if k := info.Const(c, "Ratio"); k.Type != "float32" {
+		t.Errorf("Ratio has type %q, want float32", k.Type)
+	}
+}
+
+func TestFold(t *testing.T) {
+	pkg, info, errs := check(t, `C
+	Size = 4
+	run(n int)
+		var a = Size * 2 + n
+		var b int8 = 100 + 100
+		ret 1 / (Size - 4)
+	hide(Size int)
+		ret Size + 1
+	bad()
+		ret iota
+`)
+	checkErrors(t, errs,
+		"constant 200 overflows int8",
+		"division by zero",
+		"cannot use iota outside of a constant declaration")
+
+	c := class(pkg, "C")
+	run := c.Stmts()[1].(*ast.FunctionDef)
+	a := run.Stmts()[0].(*ast.VarSet).Lines()[0].Vals.(*ast.ExprList).Exprs()[0].(*ast.Binary)
+	if v := info.Values[a.Left]; v == nil || v.String() != "8" {
+		t.Errorf("Size * 2 folded to %v, want 8", v)
+	}
+	if v, ok := info.Values[a]; ok {
+		t.Errorf("Size * 2 + n folded to %v, want it left alone", v)
+	}
+
+	hide := c.Stmts()[2].(*ast.FunctionDef)
+	ret := hide.Stmts()[0].(*ast.Return).Vals.(*ast.ExprList).Exprs()[0]
+	if v, ok := info.Values[ret]; ok {
+		t.Errorf("Size + 1 folded to %v even though the parameter hides the constant", v)
+	}
+}
+
+func TestMixins(t *testing.T) {
+	pkg, info, errs := check(t, `mix Named
+	.name string
+	.getName() string
+		ret name
+
+mix Counted
+	.count int
+	.getName() string
+		ret "counted"
+
+mix Base with Named
+	.id int
+
+Widget with Base, Named, Counted
+	.size int = 1
+	.getName() string
+		ret "widget"
+`)
+	checkErrors(t, errs)
+
+	w := class(pkg, "Widget")
+	want := map[string]string{"size": "Widget", "getName": "Widget", "id": "Base", "name": "Named", "count": "Counted"}
+	if len(info.Members[w]) != len(want) {
+		t.Fatalf("got %v members, want %v", len(info.Members[w]), len(want))
+	}
+	for name, from := range want {
+		if m := info.Member(w, name); m == nil {
+			t.Errorf("member %v is missing", name)
+		} else if m.From.Name != from {
+			t.Errorf("member %v comes from %v, want %v", name, m.From.Name, from)
+		}
+	}
+}
+
+func TestMixinErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"mix A\n\t.x int\nmix B\n\t.x int\nC with A, B\n\t.c int\n", []string{"member x of C is provided by both A and B"}},
+		{"mix A with B\n\t.a int\nmix B with C\n\t.b int\nmix C with A\n\t.c int\n", []string{"mixin cycle: A with B with C with A"}},
+		{"mix A with A\n\t.a int\n", []string{"mixin cycle: A with A"}},
+		{"B\n\t.b int\nC with B\n\t.c int\n", []string{"B is not a mixin"}},
+		{"C with Missing\n\t.c int\n", []string{"undefined: Missing"}},
+		{"C\n\t.x int\n\t.x() int\n\t\tret 1\n", []string{"member x redeclared in C"}},
+	}
+	for _, test := range tests {
+		_, _, errs := check(t, test.src)
+		checkErrors(t, errs, test.want...)
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`intf Named
+	name() string
+intf Shape with Named
+	area() float
+	scale(float, float) (float, float)
+mix HasName
+	.name() string
+		ret "x"
+Square with HasName, Shape
+	.size float
+	.area() float
+		ret size * size
+	.scale(x, y float) (float, float)
+		ret x, y
+`, nil},
+		{`intf Shape
+	area() float
+	scale(float) float
+	name() string
+	size() int
+Square with Shape
+	.name string
+	.scale(x int) float
+		ret 1.0
+	size() int
+		ret 1
+`, []string{
+			"Square does not implement Shape (missing method area)",
+			"Square does not implement Shape (wrong type for method scale: have scale(int) float, want scale(float) float)",
+			"Square does not implement Shape (name is a property, not a method)",
+			"Square does not implement Shape (method size is static, it should be .size)",
+		}},
+		{"intf A with B\n\ta()\nintf B with A\n\tb()\n", []string{"interface cycle: A with B with A"}},
+		{"intf A\n\tf() int\nintf B\n\tf() string\nintf C with A, B\n\tc()\n", []string{"duplicate method f in C"}},
+		{"mix M\n\t.m int\nintf A with M\n\ta()\n", []string{"M is not an interface"}},
+		{`intf Named
+	name() string
+Item
+	.id int
+Tag
+	.name() string
+		ret "tag"
+C
+	.n Named = Item{}
+	take(n Named)
+		ret
+	make() Named
+		ret Item{}
+	run()
+		var n Named = Item{}
+		var ok Named = Tag{}
+		var m Named?
+		n = Item{}
+		m = Item{}
+		take(Item{})
+		take(Tag{})
+		var f = fn() Named
+			ret Item{}
+`, []string{
+			"9:13: Item does not implement Named (missing method name)",
+			"13:7: Item does not implement Named (missing method name)",
+			"15:17: Item does not implement Named (missing method name)",
+			"18:7: Item does not implement Named (missing method name)",
+			"19:7: Item does not implement Named (missing method name)",
+			"20:8: Item does not implement Named (missing method name)",
+			"23:8: Item does not implement Named (missing method name)",
+		}},
+	}
+	for _, test := range tests {
+		_, _, errs := check(t, test.src)
+		checkErrors(t, errs, test.want...)
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	_, info, errs := check(t, `intf Named
+	name() string
+
+Pair<K, V>
+	.key K
+	.val V
+	.get(k K) V
+		ret val
+
+Box<T Named>
+	.items []Pair<T, int>
+	.first() T
+		ret items[0].key
+
+Item
+	.name() string
+		ret "item"
+
+main
+	main()
+		var b = Box<Item>{}
+		var s Pair<string, []int>
+`)
+	checkErrors(t, errs)
+
+	want := map[string]string{
+		"Box<Item>":           "items []Pair<Item, int>, first() Item",
+		"Pair<Item, int>":     "key Item, val int, get(Item) int",
+		"Pair<string, []int>": "key string, val []int, get(string) []int",
+	}
+	if len(info.Instances) != len(want) {
+		t.Errorf("got %v instances, want %v", len(info.Instances), len(want))
+	}
+	for typ, members := range want {
+		inst := info.Instance(typ)
+		if inst == nil {
+			t.Errorf("%v was not instantiated", typ)
+			continue
+		}
+		var got []string
+		for _, m := range inst.Members {
+			if m.Sig != nil {
+				got = append(got, m.Sig.String())
+			} else {
+				got = append(got, fmt.Sprintf("%v %v", m.Name, m.Type))
+			}
+		}
+		if strings.Join(got, ", ") != members {
+			t.Errorf("%v has members %v, want %v", typ, strings.Join(got, ", "), members)
+		}
+	}
+}
+
+func TestGenericErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"Pair<K, V>\n\t.key K\nC\n\t.p Pair<int>\n", []string{"Pair expects 2 type arguments, got 1"}},
+		{"Pair<K, V>\n\t.key K\nC\n\t.p Pair\n", []string{"generic class Pair used without type arguments"}},
+		{"D\n\t.d int\nC\n\t.p D<int>\n", []string{"D is not generic"}},
+		{"mix M\n\t.m int\nBox<T M>\n\t.t T\n", []string{"bound M of T is not an interface"}},
+		{"intf Named\n\tname() string\nBox<T Named>\n\t.t T\nC\n\t.b Box<int>\n\t.c Box<C>\n", []string{
+			"int does not satisfy Named in Box<int> (missing method name)",
+			"C does not satisfy Named in Box<C> (missing method name)",
+		}},
+		{"Node<T>\n\t.next Node<[]T>\nC\n\t.n Node<int>\n", []string{"instantiating Node<"}},
+	}
+	for _, test := range tests {
+		_, _, errs := check(t, test.src)
+		checkErrors(t, errs, test.want...)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	pkg, info, errs := check(t, `intf Named
+	name() string
+
+Pair<K, V>
+	.key K
+	.val V
+
+Box<T Named>
+	.t T
+
+Item
+	.name() string
+		ret "item"
+
+NamedItem with Named
+	.name() string
+		ret "named"
+
+Pair<int, string> as IntPair
+[]IntPair as Pairs
+Item as Thing
+Named as HasName
+int8 as small
+fn(small) IntPair as maker
+
+Limits
+	Max small = 127
+
+Holder with HasName
+	.b Box<Thing>
+	.p Pairs
+	.name() string
+		ret "holder"
+`)
+	checkErrors(t, errs)
+
+	want := map[string]string{
+		"IntPair": "Pair<int, string>",
+		"Pairs":   "[]Pair<int, string>",
+		"maker":   "fn(int8) Pair<int, string>",
+	}
+	for name, typ := range want {
+		a := pkg.Scope.Lookup(name).Decl.(*ast.Alias)
+		if got := fmt.Sprint(info.Aliases[a]); got != typ {
+			t.Errorf("%v stands for %v, want %v", name, got, typ)
+		}
+	}
+	for _, typ := range []string{"Pair<int, string>", "Box<Item>"} {
+		if info.Instance(typ) == nil {
+			t.Errorf("%v was not instantiated", typ)
+		}
+	}
+	if k := info.Const(class(pkg, "Limits"), "Max"); k.Type != "int8" {
+		t.Errorf("Max has type %q, want int8", k.Type)
+	}
+}
+
+func TestAliasErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"b as a\nc as b\na as c\n", []string{"alias cycle: a as c as b as a"}},
+		{"[]a as a\n", []string{"alias cycle: a as a"}},
+		{"a as b\nb as a\nC with a\n\t.c int\n", []string{"alias cycle: b as a as b"}},
+		{"int8 as small\nC\n\tMax small = 128\n", []string{"constant 128 overflows int8"}},
+		{"Pair<K, V>\n\t.k K\nPair<int> as P\n", []string{"Pair expects 2 type arguments, got 1"}},
+		{"Pair<K, V>\n\t.k K\nPair<int, int> as P\nC\n\t.p P<int>\n", []string{"P is not generic"}},
+		{"intf I\n\ti()\nI as J\nC with J\n\t.c int\n", []string{"C does not implement I (missing method i)"}},
+	}
+	for _, test := range tests {
+		_, _, errs := check(t, test.src)
+		checkErrors(t, errs, test.want...)
+	}
+}
+
+func TestFlow(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`C
+	abs(x int) int
+		if x < 0
+			ret -x
+		ret x
+	sign(x int) int
+		if
+			is x < 0
+				ret -1
+			is x > 0
+				ret 1
+			is _
+				ret 0
+	find(vals []int) int
+		outer: for v in vals
+			loop
+				if v > 3
+					break outer
+				ret v
+		ret -1
+	spin() int
+		loop
+			work()
+	log()
+		print("hi")
+`, nil},
+		{"C\n\tf() int\n\t\tret 1\n\t\tprint(1)\n", []string{"unreachable code"}},
+		{"C\n\tf() int\n\t\tloop\n\t\t\twork()\n\t\tret 1\n", []string{"unreachable code"}},
+		{"C\n\tf() int\n\t\tif x\n\t\t\tret 1\n", []string{"missing return at the end of f"}},
+		{"C\n\tf(x int) int\n\t\tif x\n\t\t\tis 1\n\t\t\t\tret 1\n\t\t\tis 2\n\t\t\t\tret 2\n", []string{"missing return at the end of f"}},
+		{"C\n\tf() int\n\t\tfor v in vals\n\t\t\tret v\n", []string{"missing return at the end of f"}},
+		{"C\n\tf()\n\t\tbreak\n", []string{"break is not in a loop"}},
+		{"C\n\tf()\n\t\tinner: loop\n\t\t\tbreak outer\n", []string{"invalid break label outer"}},
+	}
+	for _, test := range tests {
+		_, _, errs := check(t, test.src)
+		checkErrors(t, errs, test.want...)
+	}
+}
+
+func TestFlowParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"C\n\tg() int\n\t\tret 1 +\n",
+		"Item\n\t.x int\n\nC\n\tg() Item\n\t\tret use(Item{})\n",
+	} {
+		fAST, _ := parser.ParseTokens("test.char", lexer.LexAll(src))
+		if errs := ast.ParseErrors("test.char", fAST); len(errs) != 1 {
+			t.Fatalf("got parse errors %v for %q, want 1", errs, src)
+		}
+		pkg, _ := ast.NewPackage("test", []*ast.File{fAST.(*ast.File)})
+		_, errs := Check(pkg)
+		checkErrors(t, errs)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`Color
+	Red = iota
+	Green
+	Blue
+
+	name(c int) string
+		if c
+			is Red, Green
+				ret "warm"
+			is Color.Blue
+				ret "cool"
+		ret ""
+	code(c int) int
+		if c
+			is Red
+				ret 1
+			is _
+				ret 0
+	digit(c char) bool
+		if c
+			is '0', '1'
+				ret true
+		ret false
+`, nil},
+		{"C\n\tf(x int)\n\t\tif x\n\t\t\tis 1, 2\n\t\t\t\tg()\n\t\t\tis 3, 1 + 1\n\t\t\t\tg()\n", []string{"duplicate case 2 in is, first at 4:10"}},
+		{"C\n\tA = 1\n\tf(x int)\n\t\tif x\n\t\t\tis A\n\t\t\t\tg()\n\t\t\tis 1\n\t\t\t\tg()\n", []string{"duplicate case 1 in is, first at 5:7"}},
+		{"C\n\tf(x int)\n\t\tif x\n\t\t\tis _\n\t\t\t\tg()\n\t\t\tis 1\n\t\t\t\tg()\n", []string{"is case after is _ can never match"}},
+		{"Color\n\tRed = iota\n\tGreen\n\tBlue\n\tf(c int)\n\t\tif c\n\t\t\tis Green\n\t\t\t\tg()\n",
+			[]string{"is cases don't cover Color.Red, Color.Blue, add them or an is _"}},
+		{"C\n\tA = 1\n\tB = 2\n\tf(c int)\n\t\tif c\n\t\t\tis A\n\t\t\t\tg()\n", nil}, {`MyClass
+	greeting = "Hello from Char!"
+	First = iota
+	Second
+	Third
+
+	iota
+	AnotherFirst = iota
+	AnotherSecond
+
+	ordinal(n int) string
+		if n
+			is First
+				ret "first"
+			is Second, Third
+				ret "later"
+		ret greeting
+	another(n int) bool
+		if n
+			is AnotherFirst
+				ret true
+		ret false
+`, []string{"is cases don't cover MyClass.AnotherSecond, add them or an is _"}},
+	}
+	for _, test := range tests {
+		_, _, errs := check(t, test.src)
+		checkErrors(t, errs, test.want...)
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`Point
+	.x int
+	.y int
+
+Circle
+	.r int
+
+C
+	X = 5
+	f(p Point, n int) int
+		if p
+			is Circle X
+				ret X
+			is Point{x: 0, y}
+				ret y
+			is Point{x: 1 to 9, y: _}
+				ret 1
+		if n
+			is 1 to 9
+				ret 1
+			is 10, 20 to 30
+				ret 2
+		ret 0
+`, nil},
+		{"C\n\tf(n int)\n\t\tif n\n\t\t\tis 9 to 1\n\t\t\t\tg()\n", []string{"empty range 9 to 1"}},
+		{"C\n\tf(n int)\n\t\tif n\n\t\t\tis 1 to 9\n\t\t\t\tg()\n\t\t\tis 5\n\t\t\t\tg()\n", []string{"duplicate case 5 in is, first at 4:7"}},
+		{"C\n\tf(n int)\n\t\tif n\n\t\t\tis Missing m\n\t\t\t\tg()\n", []string{"undefined: Missing"}},
+		{"P\n\t.x int\nC\n\tf(n int)\n\t\tif n\n\t\t\tis P{z}\n\t\t\t\tg()\n", []string{"P has no property z"}},
+		{"P\n\t.x int\nC\n\tf(n int)\n\t\tif n\n\t\t\tis P{x, x}\n\t\t\t\tg()\n", []string{"x is bound more than once"}},
+		{"P\n\t.x int\nC\n\tf(n int)\n\t\tif n\n\t\t\tis P p, 1\n\t\t\t\tg()\n", []string{"cannot bind variables in an is case with more than one pattern"}},
+		{"intf I\n\ti()\nC\n\tf(n int)\n\t\tif n\n\t\t\tis I{x}\n\t\t\t\tg()\n", []string{"I is not a class"}},
+		{"C\n\tf(n int)\n\t\tif\n\t\t\tis 1 to 2\n\t\t\t\tg()\n", []string{"pattern needs a value to match"}},
+	}
+	for _, test := range tests {
+		_, _, errs := check(t, test.src)
+		checkErrors(t, errs, test.want...)
+	}
+}
+
+func TestAssigns(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{`C
+	.a, .b int = 1, 2
+	pair() (int, int)
+		ret 1, 2
+	run()
+		print(1)
+	f()
+		var x, y = pair()
+		var q, _ = C.pair()
+		x, y = y, x
+		_, y = pair()
+		x += 1
+		var m, n = other()
+		run()
+`, nil},
+		{"C\n\tf()\n\t\tvar a, b = 1\n", []string{"assignment mismatch: 2 variables but 1 value"}},
+		{"C\n\tf()\n\t\ta, b = 1, 2, 3\n", []string{"assignment mismatch: 2 variables but 3 values"}},
+		{"C\n\tpair() (int, int)\n\t\tret 1, 2\n\tf()\n\t\tvar a, b, c = pair()\n", []string{"assignment mismatch: 3 variables but pair returns 2 values"}},
+		{"C\n\tpair() (int, int)\n\t\tret 1, 2\n\tf()\n\t\tvar a, b = pair(), 1\n", []string{"multiple-value pair() (2 values) in single-value context"}},
+		{"C\n\tpair() (int, int)\n\t\tret 1, 2\n\tf()\n\t\tvar a = pair()\n", []string{"multiple-value pair() (2 values) in single-value context"}},
+		{"C\n\trun()\n\t\tprint(1)\n\tf()\n\t\tvar a = run()\n", []string{"run() (no value) used as value"}},
+		{"C\n\tf()\n\t\ta = _\n", []string{"cannot use _ as value"}},
+		{"C\n\tf()\n\t\t_ += 1\n", []string{"cannot use _ as value"}},
+		{"C\n\tf()\n\t\ta, b += 1, 2\n", []string{"assignment operation += requires single-valued expressions"}},
+		{"C\n\t.a, .b int = 1\n", []string{"assignment mismatch: 2 properties but 1 value"}},
+	}
+	for _, test := range tests {
+		_, _, errs := check(t, test.src)
+		checkErrors(t, errs, test.want...)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	pkg, info, errs := check(t, `Countdown
+	.n int
+	.next() bool
+		n -= 1
+		ret n >= 0
+	.value() int
+		ret n
+
+Pairs
+	.next() bool
+		ret false
+	.value() (int, string)
+		ret 0, ""
+
+C
+	.items []int
+	f(names []string, s string)
+		for i in range(10)
+			g(i)
+		for i, name in names
+			g(i, name)
+		for c in s
+			g(c)
+		for v in items
+			g(v)
+		for n in Countdown{n: 3}
+			g(n)
+		for k, v in Pairs{}
+			g(k, v)
+		for x in other()
+			g(x)
+`)
+	checkErrors(t, errs)
+
+	want := []ForKind{ForIterator, ForArray, ForString, ForArray, ForIterator, ForIterator, ForUnknown}
+	f := class(pkg, "C").Stmts()[1].(*ast.FunctionDef)
+	for i, s := range f.Stmts() {
+		if loop := info.Fors[s.(*ast.For)]; loop == nil || loop.Kind != want[i] {
+			t.Errorf("for %v is %+v, want kind %v", i, loop, want[i])
+		}
+	}
+}
+
+func TestLoopErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"C\n\tf(n int)\n\t\tfor i in n\n\t\t\tg(i)\n", []string{"cannot iterate over int"}},
+		{"C\n\tf(a []int)\n\t\tfor i, v, w in a\n\t\t\tg(i)\n", []string{"for over []int takes at most 2 variables, got 3"}},
+		{"C\n\tf()\n\t\tfor i, v in range(3)\n\t\t\tg(i)\n", []string{"for has 2 variables but Range.value returns 1 value"}},
+		{"D\n\t.d int\nC\n\tf()\n\t\tfor i in D{}\n\t\t\tg(i)\n", []string{"cannot iterate over D, it has no next() bool method"}},
+		{"D\n\t.next() bool\n\t\tret false\nC\n\tf()\n\t\tfor i in D{}\n\t\t\tg(i)\n", []string{"cannot iterate over D, it has no value() method"}},
+		{"C\n\tf()\n\t\tg(range())\n\t\tg(range(1, 2, 3, 4))\n", []string{"range takes 1 to 3 arguments, got 0", "range takes 1 to 3 arguments, got 4"}},
+	}
+	for _, test := range tests {
+		_, _, errs := check(t, test.src)
+		checkErrors(t, errs, test.want...)
+	}
+}
+
+func TestPrelude(t *testing.T) {
+	p := checkedPrelude()
+	if len(p.errs) > 0 {
+		t.Fatalf("prelude has errors: %v", p.errs)
+	}
+	pkg, info, errs := check(t, "C\n\t.r Range\n\t.i Iterator\n")
+	checkErrors(t, errs)
+	r, ok := pkg.Scope.Lookup("Range").Decl.(*ast.Class)
+	if !ok {
+		t.Fatal("Range isn't a class in scope")
+	}
+	if info.Member(r, "value") == nil {
+		t.Error("Range has no value member")
+	}
+}
+
+func TestPreludeNames(t *testing.T) {
+	_, _, errs := check(t, `use "prelude" as p
+
+C with prelude.Nope
+	.r prelude.Range
+	.s p.Range
+
+	f()
+		var a = Math.Pi + p.Math.E
+		var b, c = Strings.toInt("1")
+		var d, e = p.Strings.toInt("2")
+		var f = print("hi")
+		var g = prelude.println("hi")
+		var h = Strings.toInt("3")
+`)
+	checkErrors(t, errs,
+		"undefined: prelude.Nope",
+		"print() (no value) used as value",
+		"prelude.println() (no value) used as value",
+		"multiple-value Strings.toInt() (2 values) in single-value context")
+}
+
+func TestNatives(t *testing.T) {
+	_, info, errs := check(t, "C\n\tnative f() int\n\t.g() int\n\t\tret 1\n")
+	checkErrors(t, errs, "native function f can only be declared in the prelude")
+	if len(info.Flows) != 1 {
+		t.Errorf("got %v flows, want 1 for g alone", len(info.Flows))
+	}
+}
+
+func TestMaps(t *testing.T) {
+	pkg, info, errs := check(t, `C
+	.ages map<string, int> = {"ann": 31, "bob": 27}
+	.bad map<[]int, int>
+
+	f()
+		var m map<string, Iterator> = {"r": Range{}, "s": "str"}
+		var n map<int, map<int, bool>> = {1: {2: true, 3: 4}}
+		var o map<int, int> = {1: 1, 2: 2, 1: 3}
+		var x int = {1: 2}
+		for k, v in ages
+			print(k)
+		for k, v, w in ages
+			print(k)
+`)
+	checkErrors(t, errs,
+		"invalid map key type []int",
+		"cannot use string as Iterator in map value",
+		"cannot use 4 as bool in map value",
+		"duplicate key 1 in map literal, first at 8:26",
+		"cannot use map literal as int",
+		"for over map<string, int> takes at most 2 variables, got 3")
+	var kinds []ForKind
+	ast.Inspect(class(pkg, "C"), func(n ast.General) bool {
+		if f, ok := n.(*ast.For); ok {
+			kinds = append(kinds, info.Fors[f].Kind)
+		}
+		return true
+	})
+	if len(kinds) != 2 || kinds[0] != ForMap {
+		t.Errorf("got for kinds %v, want ForMap", kinds)
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	_, info, errs := check(t, `C
+	read() (string, Error)
+		ret "", nil
+
+	count() int
+		ret 1
+
+	load() (int, Error)
+		var s = read()?
+		var a, b = read()?
+		var n = Strings.toInt(s)?
+		var m = count()?
+		defer read()?
+		var k = len?
+		var f = fn() int
+			ret Strings.toInt("1")?
+		ret n, nil
+
+	run()
+		var s = read()?
+`)
+	checkErrors(t, errs,
+		"assignment mismatch: 2 variables but read returns 1 value",
+		"cannot use ? on count(), its last result isn't an Error",
+		"cannot use ? in a deferred call, it runs after the function returns",
+		"? can only follow a call",
+		"cannot use ? in fn, its last result isn't Error",
+		"cannot use ? in run, its last result isn't Error")
+
+	for fn, flow := range info.Flows {
+		if fn.Name == "run" && len(flow.Entry.Succs) != 2 {
+			t.Errorf("run's entry has %v successors, want the exit twice", len(flow.Entry.Succs))
+		}
+	}
+}
+
+func TestChans(t *testing.T) {
+	pkg, info, errs := check(t, `C
+	work(jobs chan<int>, n int)
+		for j in jobs
+			print("job")
+		for j, k in jobs
+			print("job")
+		jobs <- "one"
+		n <- 1
+		var x = <-n
+		var v, ok = <-jobs
+		spawn work(jobs, 1)
+		spawn 5
+		select
+			is v = <-jobs
+				print("got")
+			is jobs <- 2
+				print("sent")
+			is print("x")
+				print("no")
+			is _
+				print("idle")
+			is _
+				print("idle")
+`)
+	checkErrors(t, errs,
+		`cannot use "one" as int in send`,
+		"cannot send to int, it isn't a chan",
+		"cannot receive from int, it isn't a chan",
+		"spawn needs a function call",
+		"select case must send or receive",
+		"more than one is _ in select",
+		"for over chan<int> takes 1 variable, got 2")
+
+	var kinds []ForKind
+	ast.Inspect(class(pkg, "C"), func(n ast.General) bool {
+		if f, ok := n.(*ast.For); ok {
+			kinds = append(kinds, info.Fors[f].Kind)
+		}
+		return true
+	})
+	if len(kinds) != 2 || kinds[0] != ForChan {
+		t.Errorf("got for kinds %v, want ForChan", kinds)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	pkg, info, errs := check(t, `C
+	counter() fn() int
+		var n = 0
+		var step = 1
+		ret fn() int
+			n += step
+			ret n
+
+	handlers(names []string) list<fn()>
+		var hs list<fn()>
+		for name in names
+			hs.add(fn()
+				var t = fn()
+					print(name)
+				t()
+			)
+		ret hs
+
+	bad() int
+		loop
+			apply(fn() int
+				break
+			)
+		ret 1
+`)
+	checkErrors(t, errs,
+		"unreachable code",
+		"break is not in a loop",
+		"missing return at the end of fn")
+
+	var got []string
+	ast.Inspect(class(pkg, "C"), func(n ast.General) bool {
+		if fn, ok := n.(*ast.FunctionDef); ok && fn.Name == "" {
+			var caps []string
+			for _, c := range info.Captures[fn] {
+				caps = append(caps, fmt.Sprintf("%v %v %v", c.Name, c.Mutated, c.PerLoop))
+			}
+			got = append(got, strings.Join(caps, ", "))
+		}
+		return true
+	})
+	want := []string{"n true false, step false false", "name false true", "name false true", ""}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got captures %q, want %q", got, want)
+	}
+}
+
+func TestOperators(t *testing.T) {
+	pkg, info, errs := check(t, `Vec
+	.x, .y float
+
+	.plus(o Vec) Vec
+		ret Vec{x: x + o.x, y: y + o.y}
+	.negate() Vec
+		ret Vec{x: -x, y: -y}
+	.equals(o Vec) bool
+		ret x == o.x and y == o.y
+	.compare(o Vec) float
+		ret x - o.x
+
+	run(a, b Vec)
+		var c = a + b + -a
+		var d Vec = c
+		d += a
+		var same = a == b or a != c
+		var more = a > b
+		var e = a * b
+		var f = a + "b"
+		var g = a and b
+		var h = a + 1
+		var i = mk().x
+		var j = Vec{x: 1, y: 2}.plus(b)
+		var k = (a + b).x
+		var l = Failure.new("x").error()
+
+	mk() Vec
+		ret Vec{x: 1, y: 2}
+`)
+	checkErrors(t, errs,
+		"cannot use Vec.compare for >, it must return int",
+		"operator * not defined on Vec, it has no times method",
+		"cannot use string as Vec in + operand",
+		"operator and not defined on Vec",
+		"cannot use 1 as Vec in + operand")
+
+	var methods []string
+	ast.Inspect(class(pkg, "Vec"), func(n ast.General) bool {
+		if op := info.Operators[n]; op != nil {
+			methods = append(methods, op.Method)
+		}
+		return true
+	})
+	want := "[plus plus negate plus equals equals plus plus plus]"
+	if fmt.Sprint(methods) != want {
+		t.Errorf("got operator methods %v, want %v", methods, want)
+	}
+}
+
+func TestOptionals(t *testing.T) {
+	_, _, errs := check(t, `Node
+	.val int
+	.next Node?
+
+	find(n Node?, v int) Node?
+		loop
+			if n == nil
+				ret nil
+			if n.val == v
+				ret n
+			n = n.next
+
+	second(n Node?) int
+		if n != nil and n.next != nil
+			ret n.next.val
+		if n == nil or n.next == nil
+			ret 0
+		ret n.next.val
+
+	third(n Node?) int
+		if n
+			is nil
+				ret 0
+			is _
+				ret n.val
+
+	fourth(n Node?, f fn()?) int
+		if n != nil
+			n = find(n, 1)
+			print(n.val)
+		var m = find(n, 2)
+		f()
+		var x int = nil
+		ret m.val
+
+	fifth(n Node?) int
+		var f = fn()
+			n = nil
+		if n != nil
+			f()
+			ret n.val
+		ret 0
+
+	sixth(n Node?) int
+		var g = fn() Node?
+			ret n
+		if n != nil
+			ret n.val
+		ret 0
+
+	seventh() int
+		var a = find(nil, 1).val
+		var b = Node{val: 1}.next.val
+		var c = find(nil, 1).next
+		pick()()
+		ret Node{val: 2}.val
+
+	pick() fn()?
+		ret nil
+`)
+	checkErrors(t, errs,
+		"cannot use n.val, n may be nil",
+		"cannot call f, it may be nil",
+		"cannot use m.val, m may be nil",
+		"cannot use nil as int, it isn't optional",
+		"41:8: cannot use n.val, n may be nil",
+		"cannot use .val of a Node?, it may be nil",
+		"cannot use .next.val, .next may be nil",
+		"cannot use .next of a Node?, it may be nil",
+		"cannot call a fn()?, it may be nil")
+}
+
+func TestEnums(t *testing.T) {
+	pkg, info, errs := check(t, `enum Dir
+	North
+	East
+	South
+	parse
+
+Compass
+	turn(d Dir) Dir
+		if d
+			is Dir.North
+				ret Dir.East
+			is Dir.East
+				ret Dir.South
+		var name = Dir.string(d)
+		var next = Dir.parse(name)
+		ret Dir.North
+`)
+	checkErrors(t, errs,
+		"enum Dir can't have a value named parse",
+		"is cases don't cover Dir.South, add them or an is _",
+		"multiple-value Dir.parse() (2 values) in single-value context")
+
+	var names []string
+	for _, k := range info.Consts[class(pkg, "Dir")] {
+		names = append(names, fmt.Sprintf("%v=%v", k.Name, k.Value))
+	}
+	if got := strings.Join(names, " "); got != "North=0 East=1 South=2" {
+		t.Errorf("got enum values %v", got)
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	_, _, errs := check(t, `priv Helper
+	priv .secret int
+
+	priv hidden() int
+		ret 1
+
+C
+	f(h Helper, vals list<int>) string
+		var e = Failure.new("x")
+		var g = Failure{msg: "y"}
+		var it listValues<int> = vals.values()
+		var n = h.secret + Helper.hidden()
+		var m = Failure.new("z").msg
+		var s = Failure.new("z").error()
+		ret e.msg
+`)
+	checkErrors(t, errs,
+		"listValues is private to package prelude",
+		`cannot set Failure.msg, it is private to package prelude`,
+		"13:28: Failure.msg is private to package prelude",
+		"15:7: Failure.msg is private to package prelude")
+}
@@ -0,0 +1,145 @@
+package checker
+
+import (
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/token"
+)
+
+// Capture is a local of an enclosing function that an anonymous function
+// uses. Captured variables are shared, not copied, so a closure sees later
+// assignments to them and its own assignments are seen outside it.
+type Capture struct {
+	Name    string
+	Pos     token.Position // the first use
+	Mutated bool           // assigned to in the closure
+	// PerLoop is whether the variable is declared in a loop, so every
+	// iteration has a variable of its own that only the closures created
+	// in that iteration share.
+	PerLoop bool
+}
+
+// checkClosures works out what every anonymous function captures. A closure
+// uses a local of an enclosing function when it names it without declaring
+// it itself, and captures whatever the closures in it capture from further
+// out.
+func (this *checker) checkClosures() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			if fn, ok := s.(*ast.FunctionDef); ok {
+				this.closuresIn(fn, []map[string]bool{this.declsOf(fn)})
+			}
+		}
+	})
+}
+
+// closuresIn records the captures of every anonymous function directly in
+// fn, where scopes holds the declarations of fn and the functions around it,
+// innermost last. It returns what they capture from outside fn.
+func (this *checker) closuresIn(fn *ast.FunctionDef, scopes []map[string]bool) []*Capture {
+	own := scopes[len(scopes)-1]
+	var caps []*Capture
+	add := func(name string, pos token.Position, mutated bool) {
+		if _, ok := own[name]; ok {
+			return
+		}
+		perLoop, ok := false, false
+		for i := len(scopes) - 2; i >= 0 && !ok; i-- {
+			perLoop, ok = scopes[i][name]
+		}
+		if !ok {
+			return // a member, class or package
+		}
+		for _, c := range caps {
+			if c.Name == name {
+				c.Mutated = c.Mutated || mutated
+				return
+			}
+		}
+		caps = append(caps, &Capture{Name: name, Pos: pos, Mutated: mutated, PerLoop: perLoop})
+	}
+
+	ast.Inspect(fn, func(n ast.General) bool {
+		switch n := n.(type) {
+		case *ast.FunctionDef:
+			if n != fn {
+				inner := append(scopes[:len(scopes):len(scopes)], this.declsOf(n))
+				this.info.Captures[n] = this.closuresIn(n, inner)
+				for _, c := range this.info.Captures[n] {
+					add(c.Name, c.Pos, c.Mutated)
+				}
+				return false
+			}
+		case *ast.Assign:
+			for _, t := range exprsOf(n.Left) {
+				if id, ok := t.(*ast.Identifier); ok && len(id.Idents()) == 1 {
+					add(id.Idents()[0].Name, id.Pos, true)
+				}
+			}
+		case *ast.Identifier:
+			add(n.Idents()[0].Name, n.Pos, false)
+		}
+		return true
+	})
+	return caps
+}
+
+// declsOf returns the parameters and locals that fn itself declares, leaving
+// out those of the anonymous functions in it, and whether each is declared in
+// a loop.
+func (this *checker) declsOf(fn *ast.FunctionDef) map[string]bool {
+	decls := make(map[string]bool)
+	declare := func(name string, inLoop bool) {
+		if name != "_" {
+			decls[name] = decls[name] || inLoop
+		}
+	}
+	for _, p := range fn.Params() {
+		declare(p.Name(), false)
+	}
+
+	var visit func(n ast.General, inLoop bool)
+	visit = func(n ast.General, inLoop bool) {
+		ast.Inspect(n, func(m ast.General) bool {
+			switch m := m.(type) {
+			case *ast.FunctionDef:
+				return false
+			case *ast.For:
+				for _, v := range m.Vars() {
+					declare(v, true)
+				}
+				visit(m.In, inLoop)
+				for _, s := range m.Stmts() {
+					visit(s, true)
+				}
+				return false
+			case *ast.Loop:
+				for _, s := range m.Stmts() {
+					visit(s, true)
+				}
+				return false
+			case *ast.VarSetLine:
+				for _, v := range m.Vars() {
+					declare(v.Name(), inLoop)
+				}
+			case *ast.Is:
+				for _, b := range this.info.Binds[m] {
+					declare(b, inLoop)
+				}
+			}
+			return true
+		})
+	}
+	for _, s := range fn.Stmts() {
+		visit(s, false)
+	}
+	return decls
+}
+
+// funcName returns the name of fn to use in errors, which is fn for an
+// anonymous function.
+func funcName(fn *ast.FunctionDef) string {
+	if fn.Name == "" {
+		return "fn"
+	}
+	return fn.Name
+}
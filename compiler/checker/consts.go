@@ -0,0 +1,251 @@
+package checker
+
+import (
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/constant"
+	"github.com/defiant00/char/compiler/token"
+)
+
+// Const is a class constant, i.e. a property that isn't a member, along with
+// its value.
+type Const struct {
+	Name  string
+	Pos   token.Position
+	Type  string // the basic type, or "" if untyped
+	Iota  int64  // the value of iota where the constant was declared
+	Value constant.Value
+}
+
+type constState int
+
+const (
+	unevaluated constState = iota
+	evaluating
+	evaluated
+)
+
+// constDecl is a constant waiting to be evaluated.
+type constDecl struct {
+	*Const
+	file  *ast.File
+	class *ast.Class
+	typ   ast.Statement
+	expr  ast.Expression // nil if there is no value to evaluate
+	group int            // lines between bare iotas share a group
+	state constState
+}
+
+// constEnv is where a constant expression is evaluated: either the value of a
+// class constant, or an expression in a function body that may or may not be
+// constant.
+type constEnv struct {
+	file   *ast.File
+	class  *ast.Class
+	k      *constDecl      // the constant being evaluated, if any
+	locals map[string]bool // names that hide class constants
+}
+
+// checkConsts evaluates every class constant.
+//
+// Like Go, iota is the index of the constant's line, counted from the start
+// of the class or the last line with a bare iota, and a line without a value
+// repeats the previous line's values. Lines that only declare members don't
+// count.
+func (this *checker) checkConsts() {
+	this.classes(this.collectConsts)
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, k := range this.info.Consts[c] {
+			this.evalConst(this.consts[c][k.Name])
+		}
+	})
+}
+
+func (this *checker) collectConsts(f *ast.File, c *ast.Class) {
+	decls := make(map[string]*constDecl)
+	this.consts[c] = decls
+	var iota int64
+	var group int
+	var prev []ast.Expression
+	for _, s := range c.Stmts() {
+		switch s := s.(type) {
+		case *ast.Iota:
+			iota = 0
+			group++
+			prev = nil
+		case *ast.PropertySet:
+			static, members := 0, 0
+			for _, p := range s.Props() {
+				if p.Static() {
+					static++
+				} else {
+					members++
+				}
+			}
+			if static == 0 {
+				continue
+			}
+			if members > 0 {
+				this.errorf(f, s.Pos, "cannot declare constants and members together")
+			}
+
+			exprs := prev
+			if el, ok := s.Vals.(*ast.ExprList); ok {
+				exprs = el.Exprs()
+				prev = exprs
+			}
+			switch {
+			case exprs == nil:
+				this.errorf(f, s.Pos, "missing value for constant %v", s.Props()[0].Name())
+			case len(exprs) != len(s.Props()):
+				this.errorf(f, s.Pos, "%v constants but %v values", len(s.Props()), len(exprs))
+			}
+
+			for i, p := range s.Props() {
+				if !p.Static() {
+					continue
+				}
+				k := &constDecl{
+					Const: &Const{Name: p.Name(), Pos: s.Pos, Iota: iota, Value: constant.MakeUnknown()},
+					file:  f,
+					class: c,
+					typ:   p.Type(),
+					group: group,
+				}
+				if len(exprs) == len(s.Props()) {
+					k.expr = exprs[i]
+				}
+				if _, ok := decls[k.Name]; ok {
+					this.errorf(f, s.Pos, "constant %v redeclared in class %v", k.Name, c.Name)
+					continue
+				}
+				decls[k.Name] = k
+				this.info.Consts[c] = append(this.info.Consts[c], k.Const)
+			}
+			iota++
+		}
+	}
+}
+
+// evalConst computes the value of k, evaluating any constants it refers to
+// first.
+func (this *checker) evalConst(k *constDecl) constant.Value {
+	switch k.state {
+	case evaluating:
+		this.errorf(k.file, k.Pos, "initialization cycle: constant %v.%v refers to itself", k.class.Name, k.Name)
+		k.state = evaluated
+		return k.Value
+	case evaluated:
+		return k.Value
+	}
+	k.state = evaluating
+	if k.expr != nil {
+		v := this.constExpr(&constEnv{file: k.file, class: k.class, k: k}, k.expr)
+		if k.typ != nil {
+			v = this.typedConst(k, v)
+		}
+		if k.state == evaluating { // not already reported as a cycle
+			k.Value = v
+		}
+	}
+	k.state = evaluated
+	return k.Value
+}
+
+// typedConst converts v to the declared type of k.
+func (this *checker) typedConst(k *constDecl, v constant.Value) constant.Value {
+	if _, ok := k.typ.(*ast.Error); ok {
+		return constant.MakeUnknown()
+	}
+	k.Type = this.basicTypeName(k.typ)
+	if k.Type == "" {
+		this.errorf(k.file, ast.PosOf(k.typ), "invalid constant type %v", k.typ)
+		return constant.MakeUnknown()
+	}
+	v, err := convertConst(v, k.Type)
+	if err != nil {
+		this.errorf(k.file, ast.PosOf(k.expr), "%v", err)
+	}
+	return v
+}
+
+// constExpr evaluates expr in env. Outside of a constant declaration the
+// expression doesn't have to be constant, so only problems with the parts that
+// are get reported. Every constant part is recorded in the Info.
+func (this *checker) constExpr(env *constEnv, expr ast.Expression) constant.Value {
+	v := constant.MakeUnknown()
+	var err error
+	switch e := expr.(type) {
+	case *ast.Error:
+		return v // already reported by the parser
+	case *ast.Number:
+		v, err = constant.MakeFromLiteral(e.Val, token.NUMBER)
+	case *ast.String:
+		v, err = constant.MakeFromLiteral(e.Val, token.STRING)
+	case *ast.Char:
+		v, err = constant.MakeFromLiteral(e.Val, token.CHAR)
+	case *ast.Bool:
+		v = constant.MakeBool(e.Val)
+	case *ast.Iota:
+		if env.k == nil {
+			this.errorf(env.file, e.Pos, "cannot use iota outside of a constant declaration")
+			return v
+		}
+		v = constant.MakeInt64(env.k.Iota)
+	case *ast.Unary:
+		v, err = constant.UnaryOp(e.Op, this.constExpr(env, e.Expr))
+	case *ast.Binary:
+		x := this.constExpr(env, e.Left)
+		y := this.constExpr(env, e.Right)
+		v, err = constant.BinaryOp(x, e.Op, y)
+	case *ast.Identifier:
+		ref := this.lookupConst(env, e)
+		if ref == nil {
+			return this.notConst(env, expr)
+		}
+		v = this.evalConst(ref)
+	default:
+		return this.notConst(env, expr)
+	}
+
+	if err != nil {
+		this.errorf(env.file, ast.PosOf(expr), "%v", err)
+	}
+	if env.k == nil && v.Kind() != constant.Unknown {
+		this.info.Values[expr] = v
+	}
+	return v
+}
+
+// notConst handles an expression that isn't constant. That is an error in a
+// constant declaration, otherwise its children are folded instead.
+func (this *checker) notConst(env *constEnv, expr ast.Expression) constant.Value {
+	if env.k != nil {
+		this.errorf(env.file, ast.PosOf(expr), "value of constant %v cannot be computed at compile time", env.k.Name)
+	} else {
+		this.foldChildren(env, expr)
+	}
+	return constant.MakeUnknown()
+}
+
+// lookupConst resolves an identifier to a constant, either a plain name for
+// another constant of the class or Class.Name for a constant of any class in
+// the package.
+func (this *checker) lookupConst(env *constEnv, id *ast.Identifier) *constDecl {
+	parts := id.Idents()
+	if env.locals[parts[0].Name] {
+		return nil
+	}
+	for _, p := range parts {
+		if p.String() != p.Name {
+			return nil // type arguments make it a type, not a constant
+		}
+	}
+	last := len(parts) - 1
+	if last == 0 {
+		return this.consts[env.class][parts[0].Name]
+	}
+	if other := this.lookupClass(identNames(parts[:last])...); other != nil {
+		return this.consts[other][parts[last].Name]
+	}
+	return nil
+}
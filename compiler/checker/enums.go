@@ -0,0 +1,27 @@
+package checker
+
+import "github.com/defiant00/char/compiler/ast"
+
+// enumFuncs are the functions generated for every enum.
+var enumFuncs = map[string]bool{"string": true, "parse": true}
+
+// checkEnums reports enum values named after the functions generated for
+// them and drops them from the enum, so that the name only refers to the
+// function.
+func (this *checker) checkEnums() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		if !c.Enum {
+			return
+		}
+		var kept []*Const
+		for _, k := range this.info.Consts[c] {
+			if enumFuncs[k.Name] {
+				this.errorf(f, k.Pos, "enum %v can't have a value named %v, it has a generated function of that name", c.Name, k.Name)
+				delete(this.consts[c], k.Name)
+				continue
+			}
+			kept = append(kept, k)
+		}
+		this.info.Consts[c] = kept
+	})
+}
@@ -0,0 +1,284 @@
+package checker
+
+import "github.com/defiant00/char/compiler/ast"
+
+// Flow is the control-flow graph of a function. Compound statements such as
+// if and loop end the block they start in, and their bodies get blocks of
+// their own.
+type Flow struct {
+	Entry  *Block
+	Exit   *Block // reached by every ret, every ? and by falling off the end
+	Blocks []*Block
+}
+
+// Block is a run of statements that always execute together.
+type Block struct {
+	Stmts []ast.Statement
+	Succs []*Block
+	Live  bool // reachable from the entry
+}
+
+// loopTarget is an enclosing loop that a break can leave.
+type loopTarget struct {
+	label string
+	after *Block
+}
+
+// flowBuilder builds the flow of a single function.
+type flowBuilder struct {
+	*checker
+	file    *ast.File
+	flow    *Flow
+	cur     *Block
+	loops   []loopTarget
+	blockOf map[ast.Statement]*Block
+}
+
+// checkFlow builds the flow of every function with a body, reporting breaks
+// that don't leave a loop, code that can never run and functions with results
+// that can end without a ret, unless part of them failed to parse. An
+// anonymous function has a flow of its own, so a ret in it returns from it
+// and a break in it can't leave a loop around it.
+func (this *checker) checkFlow() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			if fn, ok := s.(*ast.FunctionDef); ok && !fn.Native {
+				ast.Inspect(fn, func(n ast.General) bool {
+					if fn, ok := n.(*ast.FunctionDef); ok {
+						this.info.Flows[fn] = this.buildFlow(f, fn)
+					}
+					return true
+				})
+			}
+		}
+	})
+}
+
+func (this *checker) buildFlow(f *ast.File, fn *ast.FunctionDef) *Flow {
+	b := &flowBuilder{checker: this, file: f, flow: &Flow{}, blockOf: make(map[ast.Statement]*Block)}
+	b.flow.Entry = b.newBlock()
+	b.flow.Exit = b.newBlock()
+	b.cur = b.flow.Entry
+	b.stmts(fn.Stmts())
+	end := b.cur
+	b.jump(b.flow.Exit)
+
+	markLive(b.flow.Entry)
+	b.reportDead(fn.Stmts())
+	if end.Live && len(fn.Returns()) > 0 && !hasError(fn) {
+		this.errorf(f, fn.Pos, "missing return at the end of %v", funcName(fn))
+	}
+	return b.flow
+}
+
+// hasError returns whether part of n failed to parse, which leaves its flow
+// unknown.
+func hasError(n ast.General) bool {
+	found := false
+	ast.Inspect(n, func(n ast.General) bool {
+		_, isErr := n.(*ast.Error)
+		found = found || isErr
+		return !found
+	})
+	return found
+}
+
+func (this *flowBuilder) newBlock() *Block {
+	b := &Block{}
+	this.flow.Blocks = append(this.flow.Blocks, b)
+	return b
+}
+
+// jump adds an edge from the current block to to.
+func (this *flowBuilder) jump(to *Block) {
+	this.cur.Succs = append(this.cur.Succs, to)
+}
+
+// jumpAway ends the current block with an edge to to. Whatever follows goes
+// in a new block that nothing leads to.
+func (this *flowBuilder) jumpAway(to *Block) {
+	this.jump(to)
+	this.cur = this.newBlock()
+}
+
+func (this *flowBuilder) stmts(stmts []ast.Statement) {
+	for _, s := range stmts {
+		this.stmt(s)
+	}
+}
+
+func (this *flowBuilder) stmt(s ast.Statement) {
+	this.cur.Stmts = append(this.cur.Stmts, s)
+	this.blockOf[s] = this.cur
+	if mayPropagate(s) {
+		this.jump(this.flow.Exit)
+	}
+
+	switch s := s.(type) {
+	case *ast.Return:
+		this.jumpAway(this.flow.Exit)
+	case *ast.Break:
+		if after := this.breakTarget(s); after != nil {
+			this.jumpAway(after)
+		}
+	case *ast.If:
+		this.ifStmt(s)
+	case *ast.Select:
+		this.selectStmt(s)
+	case *ast.For:
+		this.loop(s.Label, s.Stmts(), true)
+	case *ast.Loop:
+		this.loop(s.Label, s.Stmts(), false)
+	}
+}
+
+// breakTarget returns the block after the loop that s leaves.
+func (this *flowBuilder) breakTarget(s *ast.Break) *Block {
+	for i := len(this.loops) - 1; i >= 0; i-- {
+		if s.Label == "" || this.loops[i].label == s.Label {
+			return this.loops[i].after
+		}
+	}
+	if s.Label == "" {
+		this.errorf(this.file, s.Pos, "break is not in a loop")
+	} else {
+		this.errorf(this.file, s.Pos, "invalid break label %v", s.Label)
+	}
+	return nil
+}
+
+// ifStmt adds a plain if, or an if whose body is a chain of is cases. A chain
+// without an is _ case can skip all of them.
+func (this *flowBuilder) ifStmt(s *ast.If) {
+	cases := isCases(s)
+	from, after := this.cur, this.newBlock()
+	if len(cases) == 0 {
+		this.cur = this.newBlock()
+		from.Succs = append(from.Succs, this.cur)
+		this.stmts(s.Stmts())
+		this.jump(after)
+		from.Succs = append(from.Succs, after)
+		this.cur = after
+		return
+	}
+
+	hasDefault := false
+	for _, is := range cases {
+		this.cur = this.newBlock()
+		if !hasDefault { // cases after is _ never match
+			from.Succs = append(from.Succs, this.cur)
+		}
+		this.cur.Stmts = append(this.cur.Stmts, is)
+		this.blockOf[is] = this.cur
+		this.stmts(is.Stmts())
+		this.jump(after)
+		hasDefault = hasDefault || isDefault(is)
+	}
+	if !hasDefault {
+		from.Succs = append(from.Succs, after)
+	}
+	this.cur = after
+}
+
+// selectStmt adds a select, which always runs exactly one of its cases.
+func (this *flowBuilder) selectStmt(s *ast.Select) {
+	from, after := this.cur, this.newBlock()
+	for _, st := range s.Stmts() {
+		sc, ok := st.(*ast.SelectCase)
+		if !ok {
+			continue
+		}
+		this.cur = this.newBlock()
+		from.Succs = append(from.Succs, this.cur)
+		this.cur.Stmts = append(this.cur.Stmts, sc)
+		this.blockOf[sc] = this.cur
+		this.stmts(sc.Stmts())
+		this.jump(after)
+	}
+	this.cur = after
+}
+
+// isCases returns the is cases of s, which make up its whole body when there
+// are any.
+func isCases(s *ast.If) []*ast.Is {
+	var cases []*ast.Is
+	for _, st := range s.Stmts() {
+		if is, ok := st.(*ast.Is); ok {
+			cases = append(cases, is)
+		}
+	}
+	return cases
+}
+
+// isDefault returns whether is is the is _ case of a chain.
+func isDefault(is *ast.Is) bool {
+	el, ok := is.Condition.(*ast.ExprList)
+	if !ok || len(el.Exprs()) != 1 {
+		return false
+	}
+	_, ok = el.Exprs()[0].(*ast.Blank)
+	return ok
+}
+
+// loop adds a for or loop with the body stmts. A for ends when it runs out
+// of values, but a loop only ends with a break.
+func (this *flowBuilder) loop(label string, stmts []ast.Statement, isFor bool) {
+	head, after := this.newBlock(), this.newBlock()
+	this.jump(head)
+	if isFor {
+		head.Succs = append(head.Succs, after)
+	}
+
+	this.loops = append(this.loops, loopTarget{label: label, after: after})
+	this.cur = this.newBlock()
+	head.Succs = append(head.Succs, this.cur)
+	this.stmts(stmts)
+	this.jump(head)
+	this.loops = this.loops[:len(this.loops)-1]
+
+	this.cur = after
+}
+
+// markLive marks every block reachable from b.
+func markLive(b *Block) {
+	if b.Live {
+		return
+	}
+	b.Live = true
+	for _, s := range b.Succs {
+		markLive(s)
+	}
+}
+
+// reportDead reports the first statement of stmts that can never run, then
+// looks inside the statements before it.
+func (this *flowBuilder) reportDead(stmts []ast.Statement) {
+	for _, s := range stmts {
+		if !this.blockOf[s].Live {
+			this.errorf(this.file, ast.PosOf(s), "unreachable code")
+			return
+		}
+		switch s := s.(type) {
+		case *ast.If:
+			if cases := isCases(s); len(cases) > 0 {
+				for _, is := range cases {
+					if this.blockOf[is].Live { // checkMatch reports dead cases
+						this.reportDead(is.Stmts())
+					}
+				}
+			} else {
+				this.reportDead(s.Stmts())
+			}
+		case *ast.Select:
+			for _, st := range s.Stmts() {
+				if sc, ok := st.(*ast.SelectCase); ok {
+					this.reportDead(sc.Stmts())
+				}
+			}
+		case *ast.For:
+			this.reportDead(s.Stmts())
+		case *ast.Loop:
+			this.reportDead(s.Stmts())
+		}
+	}
+}
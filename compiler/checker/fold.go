@@ -0,0 +1,106 @@
+package checker
+
+import "github.com/defiant00/char/compiler/ast"
+
+// foldFuncs folds the constant expressions in every function and member
+// value, so that code generators can use the values directly.
+func (this *checker) foldFuncs() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			switch s := s.(type) {
+			case *ast.FunctionDef:
+				this.foldChildren(&constEnv{file: f, class: c, locals: localNames(s)}, s)
+			case *ast.PropertySet:
+				if !isConstSet(s) {
+					this.foldChildren(&constEnv{file: f, class: c}, s)
+				}
+			}
+		}
+	})
+}
+
+// isConstSet returns whether s declares constants rather than members.
+func isConstSet(s *ast.PropertySet) bool {
+	for _, p := range s.Props() {
+		if p.Static() {
+			return true
+		}
+	}
+	return false
+}
+
+// localNames returns every parameter and variable name declared anywhere in
+// fn, including nested functions and is case patterns. A class constant with
+// one of these names is treated as hidden throughout fn, which is never
+// wrong, only cautious.
+func localNames(fn *ast.FunctionDef) map[string]bool {
+	names := make(map[string]bool)
+	ast.Inspect(fn, func(n ast.General) bool {
+		switch n := n.(type) {
+		case *ast.FunctionDef:
+			for _, p := range n.Params() {
+				names[p.Name()] = true
+			}
+		case *ast.VarSetLine:
+			for _, v := range n.Vars() {
+				names[v.Name()] = true
+			}
+		case *ast.For:
+			for _, v := range n.Vars() {
+				names[v] = true
+			}
+		case *ast.TypePattern:
+			names[n.Name] = true
+		case *ast.ShapePattern:
+			for _, f := range n.Fields() {
+				if f.Val == nil {
+					names[f.Key] = true
+				}
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// foldChildren folds every expression below obj.
+func (this *checker) foldChildren(env *constEnv, obj ast.General) {
+	ast.Inspect(obj, func(n ast.General) bool {
+		if n == obj {
+			return true
+		}
+		switch n := n.(type) {
+		case *ast.VarSetLine:
+			this.foldVarLine(env, n)
+			return false
+		case ast.Expression:
+			this.constExpr(env, n)
+			return false
+		}
+		return true
+	})
+}
+
+// foldVarLine folds the values of a var line and checks that constant values
+// fit the basic types they are assigned to.
+func (this *checker) foldVarLine(env *constEnv, l *ast.VarSetLine) {
+	el, ok := l.Vals.(*ast.ExprList)
+	if !ok {
+		return
+	}
+	this.constExpr(env, el)
+	exprs := el.Exprs()
+	if len(exprs) != len(l.Vars()) {
+		return
+	}
+	for i, v := range l.Vars() {
+		name := this.basicTypeName(v.Type())
+		val, ok := this.info.Values[exprs[i]]
+		if name == "" || !ok {
+			continue
+		}
+		if _, err := convertConst(val, name); err != nil {
+			this.errorf(env.file, ast.PosOf(exprs[i]), "%v", err)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/defiant00/char/compiler/ast"
+	"strings"
+)
+
+// maxInstDepth limits how deeply instantiations can lead to more
+// instantiations, which stops Node<T> with a member of type Node<list<T>>
+// from going on forever.
+const maxInstDepth = 32
+
+// Instance is a generic class instantiated with concrete type arguments. Its
+// members have the type arguments substituted into their types; function
+// bodies are shared with the generic class and are generated using Subst.
+type Instance struct {
+	Class   *ast.Class
+	Type    *ast.TypeIdent           // the instantiation, e.g. Pair<int, string>
+	Subst   map[string]ast.Statement // type parameter name to type argument
+	Members []*Member
+}
+
+// checkGenerics checks the number of type arguments given to every generic
+// class and that the type parameter bounds are interfaces, then instantiates
+// every generic class used with concrete type arguments.
+func (this *checker) checkGenerics() {
+	for _, f := range this.pkg.Files() {
+		for _, s := range f.Stmts() {
+			c, isClass := s.(*ast.Class)
+			if isClass {
+				for _, tp := range c.TypeParams() {
+					if decl, ok := this.lookupDecl(f, tp.Bound()); ok {
+						if _, ok := decl.(*ast.Interface); !ok {
+							this.errorf(f, ast.PosOf(tp.Bound()), "bound %v of %v is not an interface", tp.Bound(), tp.Name())
+						}
+					}
+				}
+			}
+
+			ast.Inspect(s, func(n ast.General) bool {
+				switch n := n.(type) {
+				case *ast.TypeIdent:
+					this.checkTypeArgs(f, n, true)
+				case *ast.IdentPart:
+					if len(n.TypeParams()) > 0 {
+						this.checkTypeArgs(f, identType(n), false)
+					}
+				}
+				return true
+			})
+
+			// Generic classes are only walked once instantiated, when their
+			// type parameters are known.
+			if !isClass || len(c.TypeParams()) == 0 {
+				this.collectInstances(f, s, nil, 0)
+			}
+		}
+	}
+}
+
+// checkTypeArgs checks that t has as many type arguments as the class it
+// names has type parameters. Expressions may leave them out, as in a static
+// function call. An alias of an instantiation already has its arguments.
+func (this *checker) checkTypeArgs(f *ast.File, t *ast.TypeIdent, isType bool) {
+	if len(t.TypeParams()) > 0 && this.aliasNamed(identOf(t)) != nil {
+		this.errorf(f, t.Pos, "%v is not generic", t.Idents()[0])
+		return
+	}
+	t, ok := this.resolveType(t).(*ast.TypeIdent)
+	if !ok {
+		return
+	}
+	c := this.genericClass(t)
+	if c == nil {
+		return
+	}
+	want, got := len(c.TypeParams()), len(t.TypeParams())
+	switch {
+	case want == got:
+	case want == 0:
+		this.errorf(f, t.Pos, "%v is not generic", c.Name)
+	case got == 0:
+		if isType {
+			this.errorf(f, t.Pos, "generic class %v used without type arguments", c.Name)
+		}
+	default:
+		this.errorf(f, t.Pos, "%v expects %v type arguments, got %v", c.Name, want, got)
+	}
+}
+
+// genericClass returns the class t names, if it's declared in the package.
+func (this *checker) genericClass(t *ast.TypeIdent) *ast.Class {
+	decl, _ := this.resolveDecl(t)
+	c, _ := decl.(*ast.Class)
+	return c
+}
+
+// collectInstances instantiates every generic class used in obj, after
+// substituting the type parameters in subst.
+func (this *checker) collectInstances(f *ast.File, obj ast.General, subst map[string]ast.Statement, depth int) {
+	ast.Inspect(obj, func(n ast.General) bool {
+		var t *ast.TypeIdent
+		switch n := n.(type) {
+		case *ast.TypeIdent:
+			t = n
+		case *ast.IdentPart:
+			t = identType(n)
+		}
+		if t == nil {
+			return true
+		}
+		if t, ok := this.resolveType(substType(t, subst)).(*ast.TypeIdent); ok && len(t.TypeParams()) > 0 {
+			this.instantiate(f, t, depth)
+		}
+		return true
+	})
+}
+
+// instantiate records the instantiation t of a generic class, checking its
+// type arguments against their bounds, then instantiates everything the class
+// uses in turn.
+func (this *checker) instantiate(f *ast.File, t *ast.TypeIdent, depth int) {
+	c := this.genericClass(t)
+	if c == nil || len(c.TypeParams()) == 0 || len(c.TypeParams()) != len(t.TypeParams()) {
+		return
+	}
+	key := t.String()
+	if _, ok := this.insts[key]; ok {
+		return
+	}
+	if depth >= maxInstDepth {
+		this.errorf(f, t.Pos, "instantiating %v never ends, %v uses itself with ever larger type arguments", key, c.Name)
+		this.insts[key] = nil
+		return
+	}
+
+	inst := &Instance{Class: c, Type: t, Subst: make(map[string]ast.Statement)}
+	for i, tp := range c.TypeParams() {
+		arg := t.TypeParams()[i]
+		inst.Subst[tp.Name()] = arg
+		if tp.Bound() != nil {
+			this.checkBound(f, t, arg, tp.Bound())
+		}
+	}
+	for _, m := range this.info.Members[c] {
+		im := *m
+		im.Type = substType(m.Type, inst.Subst)
+		if m.Sig != nil {
+			im.Sig = substSig(m.Sig, inst.Subst)
+		}
+		inst.Members = append(inst.Members, &im)
+	}
+	this.insts[key] = inst
+	this.info.Instances = append(this.info.Instances, inst)
+
+	this.collectInstances(this.fileOf[c], c, inst.Subst, depth+1)
+}
+
+// checkBound checks that the type argument arg of the instantiation t
+// implements the interface bound.
+func (this *checker) checkBound(f *ast.File, t *ast.TypeIdent, arg, bound ast.Statement) {
+	decl, _ := this.resolveDecl(bound)
+	intf, ok := decl.(*ast.Interface)
+	if !ok {
+		return // already reported
+	}
+	var problems []string
+	if decl, ok := this.resolveDecl(arg); ok {
+		if c, ok := decl.(*ast.Class); ok {
+			problems = this.implements(c, intf)
+		}
+	} else if this.basicTypeName(arg) != "" {
+		for _, m := range this.info.Methods[intf] {
+			problems = append(problems, fmt.Sprintf("missing method %v", m.Name))
+		}
+	}
+	if len(problems) > 0 {
+		this.errorf(f, t.Pos, "%v does not satisfy %v in %v (%v)", arg, intf.Name, t, strings.Join(problems, ", "))
+	}
+}
+
+// identType returns the type an identifier part with type arguments names.
+func identType(ip *ast.IdentPart) *ast.TypeIdent {
+	t := &ast.TypeIdent{Pos: ip.Pos}
+	t.AddIdent(ip.Name)
+	for _, tp := range ip.TypeParams() {
+		t.AddTypeParam(tp)
+	}
+	return t
+}
+
+// identOf returns t without its type arguments.
+func identOf(t *ast.TypeIdent) *ast.TypeIdent {
+	nt := &ast.TypeIdent{Pos: t.Pos}
+	for _, id := range t.Idents() {
+		nt.AddIdent(id)
+	}
+	return nt
+}
+
+// substType returns typ with every type parameter in subst replaced by its
+// type argument.
+func substType(typ ast.Statement, subst map[string]ast.Statement) ast.Statement {
+	if len(subst) == 0 {
+		return typ
+	}
+	switch t := typ.(type) {
+	case *ast.TypeIdent:
+		if len(t.Idents()) == 1 && len(t.TypeParams()) == 0 {
+			if arg, ok := subst[t.Idents()[0]]; ok {
+				return arg
+			}
+			return t
+		}
+		nt := &ast.TypeIdent{Pos: t.Pos}
+		for _, id := range t.Idents() {
+			nt.AddIdent(id)
+		}
+		for _, tp := range t.TypeParams() {
+			nt.AddTypeParam(substType(tp, subst))
+		}
+		return nt
+	case *ast.Array:
+		return &ast.Array{Pos: t.Pos, Type: substType(t.Type, subst)}
+	case *ast.Optional:
+		return &ast.Optional{Pos: t.Pos, Type: substType(t.Type, subst)}
+	case *ast.FunctionSig:
+		nt := &ast.FunctionSig{Pos: t.Pos}
+		for _, p := range t.Params() {
+			nt.AddParam(substType(p, subst))
+		}
+		for _, r := range t.Returns() {
+			nt.AddReturn(substType(r, subst))
+		}
+		return nt
+	}
+	return typ
+}
+
+// substSig returns sig with its type parameters substituted.
+func substSig(sig *ast.IntfFuncSig, subst map[string]ast.Statement) *ast.IntfFuncSig {
+	ns := &ast.IntfFuncSig{Pos: sig.Pos, Name: sig.Name}
+	for _, p := range sig.Params() {
+		ns.AddParam(substType(p, subst))
+	}
+	for _, r := range sig.Returns() {
+		ns.AddReturn(substType(r, subst))
+	}
+	return ns
+}
@@ -0,0 +1,194 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/token"
+	"strings"
+)
+
+// checkInterfaces expands every interface into its full method set, then
+// checks that each class implements the interfaces in its withs.
+func (this *checker) checkInterfaces() {
+	for _, f := range this.pkg.Files() {
+		for _, s := range f.Stmts() {
+			if intf, ok := s.(*ast.Interface); ok {
+				this.methodSet(intf, nil)
+			}
+		}
+	}
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, w := range c.Withs() {
+			if decl, ok := this.resolveDecl(w); ok {
+				if intf, ok := decl.(*ast.Interface); ok {
+					for _, problem := range this.implements(c, intf) {
+						this.errorf(f, ast.PosOf(w), "%v does not implement %v (%v)", c.Name, intf.Name, problem)
+					}
+				}
+			}
+		}
+	})
+}
+
+// checkConversions checks that wherever a value of a class is used as an
+// interface, in an assignment, a var line, a property set, an argument or a
+// ret, the class implements the interface.
+func (this *checker) checkConversions() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			switch s := s.(type) {
+			case *ast.FunctionDef:
+				env := &typeEnv{constEnv: constEnv{file: f, class: c, locals: localNames(s)}, vars: localTypes(s)}
+				this.conversionsIn(env, s)
+			case *ast.PropertySet:
+				if !isConstSet(s) {
+					env := &typeEnv{constEnv: constEnv{file: f, class: c}}
+					for i, p := range s.Props() {
+						this.checkConversion(env, nthExpr(s.Vals, i, len(s.Props())), p.Type())
+					}
+				}
+			}
+		}
+	})
+}
+
+// conversionsIn checks the conversions in fn, where a ret converts its values
+// to the results of fn and not of the functions around it.
+func (this *checker) conversionsIn(env *typeEnv, fn *ast.FunctionDef) {
+	for _, s := range fn.Stmts() {
+		ast.Inspect(s, func(n ast.General) bool {
+			switch n := n.(type) {
+			case *ast.FunctionDef:
+				this.conversionsIn(env, n)
+				return false
+			case *ast.VarSetLine:
+				for i, v := range n.Vars() {
+					this.checkConversion(env, nthExpr(n.Vals, i, len(n.Vars())), v.Type())
+				}
+			case *ast.Assign:
+				if n.Op == token.ASSIGN {
+					targets := exprsOf(n.Left)
+					for i, t := range targets {
+						this.checkConversion(env, nthExpr(n.Right, i, len(targets)), this.typeOf(env, t))
+					}
+				}
+			case *ast.FunctionCall:
+				if _, sig := this.calledFunc(&env.constEnv, n); sig != nil {
+					for i, p := range sig.Params() {
+						this.checkConversion(env, nthExpr(n.Params, i, len(sig.Params())), p)
+					}
+				}
+			case *ast.Return:
+				for i, r := range fn.Returns() {
+					this.checkConversion(env, nthExpr(n.Vals, i, len(fn.Returns())), r)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// checkConversion checks that e, if it's an instance of a class, implements
+// want, if it's an interface or an optional one.
+func (this *checker) checkConversion(env *typeEnv, e ast.Expression, want ast.Statement) {
+	if e == nil || want == nil {
+		return
+	}
+	if opt, ok := this.resolveType(want).(*ast.Optional); ok {
+		want = opt.Type
+	}
+	intf, ok := this.typeDecl(want).(*ast.Interface)
+	if !ok {
+		return
+	}
+	c, ok := this.typeDecl(this.typeOf(env, e)).(*ast.Class)
+	if !ok {
+		return
+	}
+	for _, problem := range this.implements(c, intf) {
+		this.errorf(env.file, ast.PosOf(e), "%v does not implement %v (%v)", c.Name, intf.Name, problem)
+	}
+}
+
+// methodSet returns the methods of intf along with those of every interface
+// it embeds, expanding it first if needed. Path holds the interfaces
+// currently being expanded, in order, for reporting cycles.
+func (this *checker) methodSet(intf *ast.Interface, path []*ast.Interface) []*ast.IntfFuncSig {
+	switch this.intfs[intf] {
+	case flattening:
+		var names []string
+		for i := len(path) - 1; i >= 0; i-- {
+			names = append([]string{path[i].Name}, names...)
+			if path[i] == intf {
+				break
+			}
+		}
+		names = append(names, intf.Name)
+		this.errorf(this.fileOf[intf], intf.Pos, "interface cycle: %v", strings.Join(names, " with "))
+		return nil
+	case flattened:
+		return this.info.Methods[intf]
+	}
+	this.intfs[intf] = flattening
+	path = append(path, intf)
+
+	f := this.fileOf[intf]
+	var methods []*ast.IntfFuncSig
+	byName := make(map[string]*ast.IntfFuncSig)
+	add := func(sig *ast.IntfFuncSig, pos ast.Statement) {
+		if prev, ok := byName[sig.Name]; ok {
+			if prev != sig && !this.identicalSigs(prev, sig) {
+				this.errorf(f, ast.PosOf(pos), "duplicate method %v in %v: %v and %v", sig.Name, intf.Name, prev, sig)
+			}
+			return
+		}
+		byName[sig.Name] = sig
+		methods = append(methods, sig)
+	}
+
+	for _, s := range intf.FuncSigs() {
+		if sig, ok := s.(*ast.IntfFuncSig); ok {
+			add(sig, sig)
+		}
+	}
+	for _, w := range intf.Withs() {
+		decl, ok := this.lookupDecl(f, w)
+		if !ok {
+			continue
+		}
+		embedded, ok := decl.(*ast.Interface)
+		if !ok {
+			this.errorf(f, ast.PosOf(w), "%v is not an interface", w)
+			continue
+		}
+		for _, sig := range this.methodSet(embedded, path) {
+			add(sig, w)
+		}
+	}
+
+	this.info.Methods[intf] = methods
+	this.intfs[intf] = flattened
+	return methods
+}
+
+// implements returns a description of every method of intf that c is missing
+// or has with the wrong signature, including mixed in methods.
+func (this *checker) implements(c *ast.Class, intf *ast.Interface) []string {
+	var problems []string
+	for _, want := range this.info.Methods[intf] {
+		m := this.info.Member(c, want.Name)
+		if m == nil {
+			problems = append(problems, fmt.Sprintf("missing method %v", want.Name))
+			continue
+		}
+		switch {
+		case m.Sig == nil:
+			problems = append(problems, fmt.Sprintf("%v is a property, not a method", want.Name))
+		case m.Static:
+			problems = append(problems, fmt.Sprintf("method %v is static, it should be .%v", want.Name, want.Name))
+		case !this.identicalSigs(m.Sig, want):
+			problems = append(problems, fmt.Sprintf("wrong type for method %v: have %v, want %v", want.Name, m.Sig, want))
+		}
+	}
+	return problems
+}
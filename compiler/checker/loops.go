@@ -0,0 +1,307 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/token"
+)
+
+// ForKind is what a for loop iterates over.
+type ForKind int
+
+const (
+	ForUnknown  ForKind = iota // a type that can't be worked out here
+	ForArray                   // the values of an array, with their indexes
+	ForString                  // the chars of a string, with their indexes
+	ForIterator                // the values of an Iterator
+	ForMap                     // the keys of a map, with their values
+	ForChan                    // the values received from a chan until it's closed
+)
+
+// ForLoop describes how a for loop iterates. With one variable an array or
+// string loop gets each value, and with two it gets each index and value. A
+// map loop gets each key, and with two variables each key and value. An
+// iterator loop calls next until it returns false and assigns the results of
+// value to the variables.
+type ForLoop struct {
+	Kind  ForKind
+	Type  ast.Statement    // the type iterated over, nil if unknown
+	Value *ast.IntfFuncSig // the value method of an iterator
+}
+
+// checkLoops works out what every for loop iterates over where its type is
+// known, and checks the calls to the built-in range.
+func (this *checker) checkLoops() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			if fn, ok := s.(*ast.FunctionDef); ok {
+				env := &typeEnv{constEnv: constEnv{file: f, class: c, locals: localNames(fn)}, vars: localTypes(fn)}
+				ast.Inspect(fn, func(n ast.General) bool {
+					switch n := n.(type) {
+					case *ast.For:
+						this.info.Fors[n] = this.checkFor(env, n)
+					case *ast.FunctionCall:
+						if args := argCount(n); this.isRangeCall(env, n) && (args < 1 || args > 3) {
+							this.errorf(f, n.Pos, "range takes 1 to 3 arguments, got %v", args)
+						}
+					}
+					return true
+				})
+			}
+		}
+	})
+}
+
+func (this *checker) checkFor(env *typeEnv, s *ast.For) *ForLoop {
+	loop := &ForLoop{Type: this.typeOf(env, s.In)}
+	vars := len(s.Vars())
+	switch t := this.resolveType(loop.Type).(type) {
+	case nil:
+	case *ast.Array:
+		loop.Kind = ForArray
+	case *ast.TypeIdent:
+		if t.String() == "string" {
+			loop.Kind = ForString
+			break
+		}
+		if _, _, ok := this.mapTypes(t); ok {
+			loop.Kind = ForMap
+			break
+		}
+		if this.chanElem(t) != nil {
+			loop.Kind = ForChan
+			if vars > 1 {
+				this.errorf(env.file, s.Pos, "for over %v takes 1 variable, got %v", t, vars)
+			}
+			return loop
+		}
+		if this.basicTypeName(t) != "" {
+			this.errorf(env.file, ast.PosOf(s.In), "cannot iterate over %v", t)
+			return loop
+		}
+		next, value := this.methodOf(t, "next"), this.methodOf(t, "value")
+		switch {
+		case next == nil && value == nil && this.typeDecl(t) == nil:
+			return loop // from another package
+		case next == nil || len(next.Params()) != 0 || len(next.Returns()) != 1 || fmt.Sprint(next.Returns()[0]) != "bool":
+			this.errorf(env.file, ast.PosOf(s.In), "cannot iterate over %v, it has no next() bool method", t)
+		case value == nil || len(value.Params()) != 0:
+			this.errorf(env.file, ast.PosOf(s.In), "cannot iterate over %v, it has no value() method", t)
+		case len(value.Returns()) != vars:
+			this.errorf(env.file, s.Pos, "for has %v but %v.value returns %v", plural(vars, "variable"), t, plural(len(value.Returns()), "value"))
+		default:
+			loop.Kind = ForIterator
+			loop.Value = value
+		}
+		return loop
+	default:
+		this.errorf(env.file, ast.PosOf(s.In), "cannot iterate over %v", t)
+		return loop
+	}
+	if loop.Kind != ForUnknown && vars > 2 {
+		this.errorf(env.file, s.Pos, "for over %v takes at most 2 variables, got %v", loop.Type, vars)
+	}
+	return loop
+}
+
+// methodOf returns the signature of the instance method name of the class or
+// interface t, or nil if it has no such method.
+func (this *checker) methodOf(t *ast.TypeIdent, name string) *ast.IntfFuncSig {
+	switch d := this.typeDecl(t).(type) {
+	case *ast.Class:
+		members := this.info.Members[d]
+		if inst := this.info.Instance(t.String()); inst != nil {
+			members = inst.Members
+		}
+		for _, m := range members {
+			if m.Name == name && m.Sig != nil && !m.Static {
+				return m.Sig
+			}
+		}
+	case *ast.Interface:
+		for _, sig := range this.info.Methods[d] {
+			if sig.Name == name {
+				return sig
+			}
+		}
+	}
+	return nil
+}
+
+// typeDecl returns the class or interface t names, or nil.
+func (this *checker) typeDecl(t ast.Statement) ast.Statement {
+	decl, _ := this.resolveDecl(t)
+	switch decl.(type) {
+	case *ast.Class, *ast.Interface:
+		return decl
+	}
+	return nil
+}
+
+// typeEnv is a constEnv that also knows the declared types of the local
+// variables.
+type typeEnv struct {
+	constEnv
+	vars map[string]ast.Statement
+}
+
+// localTypes returns the type of every parameter and variable of fn declared
+// with one. A name declared more than once with different types has no type.
+func localTypes(fn *ast.FunctionDef) map[string]ast.Statement {
+	types := make(map[string]ast.Statement)
+	add := func(name string, typ ast.Statement) {
+		if prev, ok := types[name]; ok && (prev == nil || typ == nil || fmt.Sprint(prev) != fmt.Sprint(typ)) {
+			typ = nil
+		}
+		types[name] = typ
+	}
+	params := fn.Params()
+	for i, typ := range paramTypes(fn) {
+		add(params[i].Name(), typ)
+	}
+	ast.Inspect(fn, func(n ast.General) bool {
+		switch n := n.(type) {
+		case *ast.VarSetLine:
+			for _, v := range n.Vars() {
+				add(v.Name(), v.Type())
+			}
+		case *ast.For:
+			for _, v := range n.Vars() {
+				add(v, nil)
+			}
+		}
+		return true
+	})
+	return types
+}
+
+// typeOf returns the type of e where it can be worked out without full type
+// inference: locals and properties declared with a type, constructors, string
+// literals, calls to functions of the package and the built-in range, members
+// of values of known types, and operators on class instances.
+func (this *checker) typeOf(env *typeEnv, e ast.Expression) ast.Statement {
+	switch e := e.(type) {
+	case *ast.String:
+		return ast.NewTypeIdent(e.Pos, "string")
+	case *ast.ArrayCons:
+		return &ast.Array{Pos: e.Pos, Type: e.Type}
+	case *ast.Constructor:
+		if id, ok := e.Type.(*ast.Identifier); ok {
+			return identTypeOf(id)
+		}
+	case *ast.Identifier:
+		parts := e.Idents()
+		if len(parts) != 1 {
+			return nil
+		}
+		if typ, ok := env.vars[parts[0].Name]; ok {
+			return typ
+		}
+		if env.locals[parts[0].Name] {
+			return nil
+		}
+		if m := this.info.Member(env.class, parts[0].Name); m != nil && m.Sig == nil {
+			return m.Type
+		}
+	case *ast.FunctionCall:
+		if this.isRangeCall(env, e) {
+			return ast.NewTypeIdent(e.Pos, "Range")
+		}
+		if _, sig := this.calledFunc(&env.constEnv, e); sig != nil && len(sig.Returns()) == 1 {
+			return sig.Returns()[0]
+		}
+	case *ast.Receive:
+		if typ := this.typeOf(env, e.Chan); typ != nil {
+			return this.chanElem(typ)
+		}
+	case *ast.Propagate:
+		if _, sig := this.calledFunc(&env.constEnv, e); sig != nil && len(sig.Returns()) == 1 {
+			return sig.Returns()[0]
+		}
+	case *ast.Binary:
+		if e.Op == token.DOT {
+			return this.memberType(env, e)
+		}
+		return this.operatorType(env, e.Op, e.Left, false)
+	case *ast.Unary:
+		return this.operatorType(env, e.Op, e.Expr, true)
+	}
+	return nil
+}
+
+// memberType returns the type of the member that b gets from the value of its
+// left operand: a property, or the result of a method that returns one.
+// Getting a member of an optional gets it from the value it holds.
+func (this *checker) memberType(env *typeEnv, b *ast.Binary) ast.Statement {
+	names, call := memberNames(b.Right)
+	if names == nil {
+		return nil
+	}
+	typ := this.typeOf(env, b.Left)
+	last := len(names) - 1
+	for _, name := range names[:last] {
+		typ = this.propertyType(typ, name)
+	}
+	if !call {
+		return this.propertyType(typ, names[last])
+	}
+	if opt, ok := this.resolveType(typ).(*ast.Optional); ok {
+		typ = opt.Type
+	}
+	t, ok := this.resolveType(typ).(*ast.TypeIdent)
+	if !ok {
+		return nil
+	}
+	if sig := this.methodOf(t, names[last]); sig != nil && len(sig.Returns()) == 1 {
+		return sig.Returns()[0]
+	}
+	return nil
+}
+
+// memberNames returns the names that e, the right operand of a ., gets in
+// turn, and whether it calls the last of them.
+func memberNames(e ast.Expression) ([]string, bool) {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		return identNames(e.Idents()), false
+	case *ast.FunctionCall:
+		if id, ok := e.Function.(*ast.Identifier); ok {
+			return identNames(id.Idents()), true
+		}
+	}
+	return nil, false
+}
+
+// isRangeCall returns whether call is to the built-in range, which is only
+// built in where nothing else is named range.
+func (this *checker) isRangeCall(env *typeEnv, call *ast.FunctionCall) bool {
+	id, ok := call.Function.(*ast.Identifier)
+	if !ok || len(id.Idents()) != 1 || id.Idents()[0].String() != "range" {
+		return false
+	}
+	if _, ok := env.vars["range"]; ok || env.locals["range"] || this.info.Member(env.class, "range") != nil {
+		return false
+	}
+	return this.pkg.Scope.Lookup("range") == nil
+}
+
+func argCount(call *ast.FunctionCall) int {
+	if call.Params == nil {
+		return 0
+	}
+	return len(exprsOf(call.Params))
+}
+
+// identTypeOf returns the type an identifier names, such as the type of a
+// constructor.
+func identTypeOf(id *ast.Identifier) *ast.TypeIdent {
+	t := &ast.TypeIdent{Pos: id.Pos}
+	parts := id.Idents()
+	for _, p := range parts {
+		t.AddIdent(p.Name)
+	}
+	for _, tp := range parts[len(parts)-1].TypeParams() {
+		t.AddTypeParam(tp)
+	}
+	return t
+}
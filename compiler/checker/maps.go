@@ -0,0 +1,173 @@
+package checker
+
+import "github.com/defiant00/char/compiler/ast"
+
+// mapClass is the prelude class that map types instantiate.
+const mapClass = "map"
+
+// checkMaps checks that map key types can be compared, and that the keys and
+// values of every map literal assigned to something of a known map type are
+// of its key and value types, without any constant key given twice.
+func (this *checker) checkMaps() {
+	for _, f := range this.pkg.Files() {
+		ast.Inspect(f, func(n ast.General) bool {
+			switch n := n.(type) {
+			case *ast.TypeIdent:
+				this.checkMapKey(f, n)
+			case *ast.IdentPart:
+				this.checkMapKey(f, identType(n))
+			}
+			return true
+		})
+	}
+
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			switch s := s.(type) {
+			case *ast.FunctionDef:
+				env := &typeEnv{constEnv: constEnv{file: f, class: c, locals: localNames(s)}, vars: localTypes(s)}
+				ast.Inspect(s, func(n ast.General) bool {
+					switch n := n.(type) {
+					case *ast.VarSetLine:
+						for i, v := range n.Vars() {
+							this.checkMapValue(env, v.Type(), nthExpr(n.Vals, i, len(n.Vars())))
+						}
+					case *ast.Assign:
+						targets := exprsOf(n.Left)
+						for i, t := range targets {
+							this.checkMapValue(env, this.typeOf(env, t), nthExpr(n.Right, i, len(targets)))
+						}
+					}
+					return true
+				})
+			case *ast.PropertySet:
+				if !isConstSet(s) {
+					env := &typeEnv{constEnv: constEnv{file: f, class: c}}
+					for i, p := range s.Props() {
+						this.checkMapValue(env, p.Type(), nthExpr(s.Vals, i, len(s.Props())))
+					}
+				}
+			}
+		}
+	})
+}
+
+// nthExpr returns the ith of n values in vals, or nil if vals doesn't hold
+// exactly n values.
+func nthExpr(vals ast.Expression, i, n int) ast.Expression {
+	if vals == nil {
+		return nil
+	}
+	exprs := exprsOf(vals)
+	if len(exprs) != n {
+		return nil
+	}
+	return exprs[i]
+}
+
+// mapTypes returns the key and value types of typ if it is a map.
+func (this *checker) mapTypes(typ ast.Statement) (key, val ast.Statement, ok bool) {
+	args := this.preludeTypeArgs(typ, mapClass, 2)
+	if args == nil {
+		return nil, nil, false
+	}
+	return args[0], args[1], true
+}
+
+// checkMapKey reports a map type whose keys can't be compared.
+func (this *checker) checkMapKey(f *ast.File, t *ast.TypeIdent) {
+	key, _, ok := this.mapTypes(t)
+	if !ok {
+		return
+	}
+	switch this.resolveType(key).(type) {
+	case *ast.Array, *ast.FunctionSig:
+		this.errorf(f, t.Pos, "invalid map key type %v", key)
+		return
+	}
+	if _, _, isMap := this.mapTypes(key); isMap {
+		this.errorf(f, t.Pos, "invalid map key type %v", key)
+	}
+}
+
+// checkMapValue checks e if it's a map literal assigned to something of the
+// type typ.
+func (this *checker) checkMapValue(env *typeEnv, typ ast.Statement, e ast.Expression) {
+	lit, ok := e.(*ast.MapValueList)
+	if !ok || typ == nil {
+		return
+	}
+	key, val, ok := this.mapTypes(typ)
+	if !ok {
+		if this.basicTypeName(typ) != "" || this.typeDecl(typ) != nil {
+			this.errorf(env.file, lit.Pos, "cannot use map literal as %v", typ)
+		}
+		return
+	}
+
+	var seen []ast.Expression
+	for _, el := range exprsOf(lit.Vals) {
+		entry, ok := el.(*ast.MapEntry)
+		if !ok {
+			continue
+		}
+		if v, ok := this.info.Values[entry.Key]; ok {
+			for _, prev := range seen {
+				if sameValue(this.info.Values[prev], v) {
+					this.errorf(env.file, entry.Pos, "duplicate key %v in map literal, first at %v", v, ast.PosOf(prev))
+					break
+				}
+			}
+			seen = append(seen, entry.Key)
+		}
+		this.checkElem(env, entry.Key, key, "map key")
+		this.checkElem(env, entry.Val, val, "map value")
+	}
+}
+
+// checkElem checks that e, which is used as described by what, such as a map
+// key, can be used as a want.
+func (this *checker) checkElem(env *typeEnv, e ast.Expression, want ast.Statement, what string) {
+	if _, ok := e.(*ast.MapValueList); ok {
+		this.checkMapValue(env, want, e)
+		return
+	}
+	if v, ok := this.info.Values[e]; ok {
+		typ := want
+		if opt, ok := this.resolveType(want).(*ast.Optional); ok {
+			typ = opt.Type
+		}
+		if name := this.basicTypeName(typ); name != "" {
+			if _, err := convertConst(v, name); err != nil {
+				this.errorf(env.file, ast.PosOf(e), "%v in %v", err, what)
+			}
+			return
+		}
+		if this.typeDecl(typ) != nil && this.typeOf(env, e) == nil {
+			this.errorf(env.file, ast.PosOf(e), "cannot use %v as %v in %v", v, want, what)
+			return
+		}
+	}
+	known := this.basicTypeName(want) != "" || this.typeDecl(want) != nil
+	if typ := this.typeOf(env, e); typ != nil && known && !this.assignable(typ, want) {
+		this.errorf(env.file, ast.PosOf(e), "cannot use %v as %v in %v", typ, want, what)
+	}
+}
+
+// assignable returns whether a value of type typ can be used as a want: the
+// types are identical, want is an interface that typ's class implements, or
+// want is an optional of a type that typ can be used as.
+func (this *checker) assignable(typ, want ast.Statement) bool {
+	if this.identical(typ, want) {
+		return true
+	}
+	if opt, ok := this.resolveType(want).(*ast.Optional); ok {
+		return this.assignable(typ, opt.Type)
+	}
+	intf, ok := this.typeDecl(want).(*ast.Interface)
+	if !ok {
+		return false
+	}
+	c, ok := this.typeDecl(typ).(*ast.Class)
+	return ok && len(this.implements(c, intf)) == 0
+}
@@ -0,0 +1,171 @@
+package checker
+
+import (
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/constant"
+	"github.com/defiant00/char/compiler/token"
+	"strings"
+)
+
+// checkMatches checks the is cases of every if that matches a value: no two
+// cases may have the same constant, no case may follow is _, and a match on
+// the constants of an iota enum must cover all of them or have an is _.
+func (this *checker) checkMatches() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			if fn, ok := s.(*ast.FunctionDef); ok {
+				env := &constEnv{file: f, class: c, locals: localNames(fn)}
+				ast.Inspect(fn, func(n ast.General) bool {
+					if s, ok := n.(*ast.If); ok && s.Condition != nil {
+						this.checkMatch(env, s)
+					}
+					return true
+				})
+			}
+		}
+	})
+}
+
+// matchCase is a constant, or a range of constants, that an is case matches.
+type matchCase struct {
+	expr ast.Expression
+	val  constant.Value
+	high constant.Value // the end of a range, nil for a single constant
+	k    *constDecl     // the constant expr names, if any
+}
+
+// covers returns whether the case matches v.
+func (this matchCase) covers(v constant.Value) bool {
+	if this.high == nil {
+		return sameValue(this.val, v)
+	}
+	return compares(this.val, token.LT_EQUAL, v) && compares(v, token.LT_EQUAL, this.high)
+}
+
+func (this *checker) checkMatch(env *constEnv, s *ast.If) {
+	cases := isCases(s)
+	var seen []matchCase
+	enum := true
+	for i, is := range cases {
+		if isDefault(is) {
+			if i+1 < len(cases) {
+				this.errorf(env.file, cases[i+1].Pos, "is case after is _ can never match")
+			}
+			return
+		}
+		el, ok := is.Condition.(*ast.ExprList)
+		if !ok {
+			return
+		}
+		for _, e := range el.Exprs() {
+			if r, ok := e.(*ast.RangePattern); ok {
+				low, okLow := this.info.Values[r.Low]
+				high, okHigh := this.info.Values[r.High]
+				if okLow && okHigh {
+					seen = append(seen, matchCase{expr: e, val: low, high: high})
+				}
+				enum = false
+				continue
+			}
+			v, ok := this.info.Values[e]
+			if !ok {
+				enum = false
+				continue
+			}
+			mc := matchCase{expr: e, val: v}
+			if id, ok := e.(*ast.Identifier); ok {
+				mc.k = this.lookupConst(env, id)
+			}
+			enum = enum && mc.k != nil
+			for _, prev := range seen {
+				if prev.covers(v) {
+					this.errorf(env.file, ast.PosOf(e), "duplicate case %v in is, first at %v", caseName(mc), ast.PosOf(prev.expr))
+					break
+				}
+			}
+			seen = append(seen, mc)
+		}
+	}
+	if enum && len(seen) > 0 {
+		this.checkExhaustive(env, s, seen)
+	}
+}
+
+// checkExhaustive reports the constants of the iota enum the cases are drawn
+// from that none of them match.
+func (this *checker) checkExhaustive(env *constEnv, s *ast.If, seen []matchCase) {
+	first := seen[0].k
+	members := this.enumOf(first)
+	if members == nil {
+		return
+	}
+	var missing []string
+	for _, k := range members {
+		found := false
+		for _, mc := range seen {
+			if mc.k.class != first.class || mc.k.group != first.group {
+				return // not a match on a single enum
+			}
+			found = found || sameValue(mc.val, k.Value)
+		}
+		if !found {
+			missing = append(missing, first.class.Name+"."+k.Name)
+		}
+	}
+	if len(missing) > 0 {
+		this.errorf(env.file, s.Pos, "is cases don't cover %v, add them or an is _", strings.Join(missing, ", "))
+	}
+}
+
+// enumOf returns the constants in the same iota group as k from the first one
+// that uses iota on, making them an enum, or nil if none of them do. Constants
+// declared before it, such as a greeting string, aren't part of the enum.
+func (this *checker) enumOf(k *constDecl) []*constDecl {
+	var members []*constDecl
+	for _, c := range this.info.Consts[k.class] {
+		other := this.consts[k.class][c.Name]
+		if other.group != k.group {
+			continue
+		}
+		if members == nil && !usesIota(other.expr) {
+			continue
+		}
+		members = append(members, other)
+	}
+	return members
+}
+
+// usesIota returns whether expr refers to iota.
+func usesIota(expr ast.Expression) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.General) bool {
+		_, isIota := n.(*ast.Iota)
+		found = found || isIota
+		return !found
+	})
+	return found
+}
+
+// sameValue returns whether two constants are equal. Values of different
+// kinds never are.
+func sameValue(x, y constant.Value) bool {
+	return compares(x, token.EQUAL, y)
+}
+
+// compares returns whether the comparison x op y holds. Comparisons between
+// values of different kinds never do.
+func compares(x constant.Value, op token.Type, y constant.Value) bool {
+	res, err := constant.BinaryOp(x, op, y)
+	if err != nil {
+		return false
+	}
+	b, _ := constant.BoolVal(res)
+	return b
+}
+
+func caseName(mc matchCase) string {
+	if mc.k != nil {
+		return mc.k.Name
+	}
+	return mc.val.String()
+}
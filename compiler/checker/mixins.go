@@ -0,0 +1,168 @@
+package checker
+
+import (
+	"github.com/defiant00/char/compiler/ast"
+	"strings"
+)
+
+// Member is a property or function of a class, either declared by the class
+// itself or mixed into it.
+type Member struct {
+	Name   string
+	Static bool             // a function that isn't called on an instance
+	Type   ast.Statement    // the type of a property, nil for functions
+	Sig    *ast.IntfFuncSig // the signature of a function, nil for properties
+	Decl   ast.Statement    // the *ast.PropertySet or *ast.FunctionDef
+	From   *ast.Class       // the class or mixin that declares it
+}
+
+type mixState int
+
+const (
+	unflattened mixState = iota
+	flattening
+	flattened
+)
+
+// flattenMixins works out the members of every class. A class gets the
+// members of each mixin in its withs, and each mixin the members of its own
+// withs in turn. Declaring a member in the class body overrides any mixed in
+// member of the same name, which is also the only way to settle two mixins
+// providing the same name.
+func (this *checker) flattenMixins() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		this.flatten(c, nil)
+	})
+}
+
+// flatten returns the members of c, flattening it first if needed. Path holds
+// the mixins currently being flattened, in order, for reporting cycles.
+func (this *checker) flatten(c *ast.Class, path []*ast.Class) []*Member {
+	switch this.mixing[c] {
+	case flattening:
+		var names []string
+		for i := len(path) - 1; i >= 0; i-- {
+			names = append([]string{path[i].Name}, names...)
+			if path[i] == c {
+				break
+			}
+		}
+		names = append(names, c.Name)
+		this.errorf(this.fileOf[c], c.Pos, "mixin cycle: %v", strings.Join(names, " with "))
+		return nil
+	case flattened:
+		return this.info.Members[c]
+	}
+	this.mixing[c] = flattening
+	path = append(path, c)
+
+	members := this.ownMembers(c)
+	own := make(map[string]bool)
+	for _, m := range members {
+		own[m.Name] = true
+	}
+
+	mixedIn := make(map[string]*Member)
+	conflicts := make(map[string]bool)
+	for _, w := range c.Withs() {
+		mixin := this.mixinOf(c, w)
+		if mixin == nil {
+			continue
+		}
+		for _, m := range this.flatten(mixin, path) {
+			if own[m.Name] {
+				continue
+			}
+			if prev, ok := mixedIn[m.Name]; ok {
+				if prev != m && !conflicts[m.Name] {
+					this.errorf(this.fileOf[c], ast.PosOf(w), "member %v of %v is provided by both %v and %v, override it in %v to choose one",
+						m.Name, c.Name, prev.From.Name, m.From.Name, c.Name)
+					conflicts[m.Name] = true
+				}
+				continue
+			}
+			mixedIn[m.Name] = m
+			members = append(members, m)
+		}
+	}
+
+	this.info.Members[c] = members
+	this.mixing[c] = flattened
+	return members
+}
+
+// ownMembers returns the members declared in the body of c.
+func (this *checker) ownMembers(c *ast.Class) []*Member {
+	var members []*Member
+	seen := make(map[string]bool)
+	add := func(m *Member) {
+		if seen[m.Name] {
+			this.errorf(this.fileOf[c], ast.PosOf(m.Decl), "member %v redeclared in %v", m.Name, c.Name)
+			return
+		}
+		seen[m.Name] = true
+		members = append(members, m)
+	}
+	for _, s := range c.Stmts() {
+		switch s := s.(type) {
+		case *ast.PropertySet:
+			for _, p := range s.Props() {
+				if !p.Static() { // static properties are constants
+					add(&Member{Name: p.Name(), Type: p.Type(), Decl: s, From: c})
+				}
+			}
+		case *ast.FunctionDef:
+			add(&Member{Name: s.Name, Static: s.Static, Sig: sigOf(s), Decl: s, From: c})
+		}
+	}
+	return members
+}
+
+// mixinOf returns the mixin that w, one of the withs of c, refers to. Withs
+// naming interfaces or types from other packages return nil.
+func (this *checker) mixinOf(c *ast.Class, w ast.Statement) *ast.Class {
+	decl, ok := this.lookupDecl(this.fileOf[c], w)
+	if !ok {
+		return nil
+	}
+	switch d := decl.(type) {
+	case *ast.Class:
+		if d.Mixin {
+			return d
+		}
+	case *ast.Interface:
+		return nil
+	}
+	this.errorf(this.fileOf[c], ast.PosOf(w), "%v is not a mixin", w)
+	return nil
+}
+
+// lookupDecl returns the declaration in the package that the type typ names.
+// It returns false for types that can't be looked up here, such as types
+// from other packages, reporting an error for names that aren't declared.
+func (this *checker) lookupDecl(f *ast.File, typ ast.Statement) (ast.Statement, bool) {
+	decl, ok := this.resolveDecl(typ)
+	if t, isIdent := this.resolveType(typ).(*ast.TypeIdent); !ok && isIdent {
+		_, basic := basicTypes[t.Idents()[0]]
+		if obj, rest := this.lookupName(t.Idents()); obj == nil && len(rest) == 0 && !basic {
+			this.errorf(f, t.Pos, "undefined: %v", strings.Join(t.Idents(), "."))
+		}
+	}
+	return decl, ok
+}
+
+// resolveDecl is lookupDecl without the error. Aliases are followed to the
+// declaration of the type they stand for, and aliases in a cycle name
+// nothing.
+func (this *checker) resolveDecl(typ ast.Statement) (ast.Statement, bool) {
+	t, ok := this.resolveType(typ).(*ast.TypeIdent)
+	if !ok {
+		return nil, false
+	}
+	if obj, rest := this.lookupName(t.Idents()); obj != nil && len(rest) == 0 {
+		if _, isAlias := obj.Decl.(*ast.Alias); !isAlias {
+			return obj.Decl, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,175 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/token"
+)
+
+// Operator is an operator applied to an instance of a class, which calls one
+// of its instance methods with the right operand, if any, as the argument.
+// != calls equals and negates the result, and <, >, <= and >= compare the
+// result of compare to 0.
+type Operator struct {
+	Class  *ast.Class
+	Method string
+	Sig    *ast.IntfFuncSig
+}
+
+// operatorMethods holds the instance method that every operator that can be
+// overloaded calls, by operator. - is sub between two operands and negate
+// before one.
+var operatorMethods = map[token.Type]string{
+	token.ADD:         "plus",
+	token.SUB:         "minus",
+	token.MUL:         "times",
+	token.DIV:         "div",
+	token.MOD:         "rem",
+	token.LSHIFT:      "shl",
+	token.RSHIFT:      "shr",
+	token.B_AND:       "bitAnd",
+	token.B_OR:        "bitOr",
+	token.B_XOR:       "bitXor",
+	token.EQUAL:       "equals",
+	token.NOT_EQUAL:   "equals",
+	token.LEFT_CARET:  "compare",
+	token.RIGHT_CARET: "compare",
+	token.LT_EQUAL:    "compare",
+	token.GT_EQUAL:    "compare",
+}
+
+// unaryMethods holds the instance method that every unary operator calls.
+var unaryMethods = map[token.Type]string{
+	token.SUB: "negate",
+	token.NOT: "not",
+}
+
+// fixedOperators holds the operators that can't be overloaded, which can't be
+// applied to instances of classes at all. Other operations on them, such as
+// getting a member with ., as and is, aren't operators that call methods.
+var fixedOperators = map[token.Type]bool{
+	token.AND: true,
+	token.OR:  true,
+}
+
+// assignOps holds the operator of every assignment operation.
+var assignOps = map[token.Type]token.Type{
+	token.ADD_ASSIGN:    token.ADD,
+	token.SUB_ASSIGN:    token.SUB,
+	token.MUL_ASSIGN:    token.MUL,
+	token.DIV_ASSIGN:    token.DIV,
+	token.MOD_ASSIGN:    token.MOD,
+	token.B_AND_ASSIGN:  token.B_AND,
+	token.B_OR_ASSIGN:   token.B_OR,
+	token.B_XOR_ASSIGN:  token.B_XOR,
+	token.LSHIFT_ASSIGN: token.LSHIFT,
+	token.RSHIFT_ASSIGN: token.RSHIFT,
+}
+
+// checkOperators resolves every operator whose left, or only, operand is of a
+// known class to the method it calls. The method must take the right operand
+// and return a single result, which for equals is a bool and for compare an
+// int. A class without an equals method is compared by identity, and the
+// logical and and or can't be overloaded.
+func (this *checker) checkOperators() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			if fn, ok := s.(*ast.FunctionDef); ok {
+				env := &typeEnv{constEnv: constEnv{file: f, class: c, locals: localNames(fn)}, vars: localTypes(fn)}
+				ast.Inspect(fn, func(n ast.General) bool {
+					switch n := n.(type) {
+					case *ast.Binary:
+						this.checkOperator(env, n, n.Op, n.Left, n.Right)
+					case *ast.Unary:
+						this.checkOperator(env, n, n.Op, n.Expr, nil)
+					case *ast.Assign:
+						targets, vals := exprsOf(n.Left), exprsOf(n.Right)
+						if op, ok := assignOps[n.Op]; ok && len(targets) == 1 && len(vals) == 1 {
+							this.checkOperator(env, n, op, targets[0], vals[0])
+						}
+					}
+					return true
+				})
+			}
+		}
+	})
+}
+
+// checkOperator checks op applied to x, and y if it's a binary operator, and
+// records the method that n calls.
+func (this *checker) checkOperator(env *typeEnv, n ast.General, op token.Type, x, y ast.Expression) {
+	typ, ok := this.resolveType(this.typeOf(env, x)).(*ast.TypeIdent)
+	if !ok {
+		return
+	}
+	c, ok := this.typeDecl(typ).(*ast.Class)
+	if !ok {
+		return
+	}
+	methods, params := operatorMethods, 1
+	if y == nil {
+		methods, params = unaryMethods, 0
+	}
+	name, ok := methods[op]
+	if !ok {
+		if fixedOperators[op] {
+			this.errorf(env.file, ast.PosOf(n), "operator %v not defined on %v", op, typ)
+		}
+		return
+	}
+	sig := this.methodOf(typ, name)
+	if sig == nil {
+		if op != token.EQUAL && op != token.NOT_EQUAL {
+			this.errorf(env.file, ast.PosOf(n), "operator %v not defined on %v, it has no %v method", op, typ, name)
+		}
+		return
+	}
+
+	want := ""
+	switch name {
+	case "equals":
+		want = "bool"
+	case "compare":
+		want = "int"
+	}
+	switch {
+	case len(sig.Params()) != params:
+		this.errorf(env.file, ast.PosOf(n), "cannot use %v.%v for %v, it must take %v", typ, name, op, plural(params, "parameter"))
+		return
+	case len(sig.Returns()) != 1:
+		this.errorf(env.file, ast.PosOf(n), "cannot use %v.%v for %v, it must return 1 value", typ, name, op)
+		return
+	case want != "" && fmt.Sprint(sig.Returns()[0]) != want:
+		this.errorf(env.file, ast.PosOf(n), "cannot use %v.%v for %v, it must return %v", typ, name, op, want)
+		return
+	}
+	if y != nil {
+		this.checkElem(env, y, sig.Params()[0], fmt.Sprintf("%v operand", op))
+	}
+	this.info.Operators[n] = &Operator{Class: c, Method: name, Sig: sig}
+}
+
+// operatorType returns the type of op applied to x, and a right operand unless
+// unary, if x is an instance of a class with a method for it.
+func (this *checker) operatorType(env *typeEnv, op token.Type, x ast.Expression, unary bool) ast.Statement {
+	typ, ok := this.resolveType(this.typeOf(env, x)).(*ast.TypeIdent)
+	if !ok {
+		return nil
+	}
+	methods := operatorMethods
+	if unary {
+		methods = unaryMethods
+	}
+	name, ok := methods[op]
+	if !ok {
+		return nil
+	}
+	sig := this.methodOf(typ, name)
+	switch {
+	case sig == nil || len(sig.Returns()) != 1:
+		return nil
+	case name == "equals" || name == "compare":
+		return ast.NewTypeIdent(typ.Pos, "bool")
+	}
+	return sig.Returns()[0]
+}
@@ -0,0 +1,494 @@
+package checker
+
+import (
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/token"
+	"strings"
+)
+
+// nonNil is the set of values, written as paths such as n.next, that are
+// known not to be nil at a point in a function.
+type nonNil map[string]bool
+
+// with returns the set along with paths.
+func (this nonNil) with(paths ...string) nonNil {
+	nn := make(nonNil, len(this)+len(paths))
+	for p := range this {
+		nn[p] = true
+	}
+	for _, p := range paths {
+		nn[p] = true
+	}
+	return nn
+}
+
+// known returns nn along with paths, leaving out those through a local that
+// an anonymous function assigns, as calling it may change the local at any
+// time.
+func (this *checker) known(nn nonNil, paths ...string) nonNil {
+	var kept []string
+	for _, p := range paths {
+		if !this.shared[strings.Split(p, ".")[0]] {
+			kept = append(kept, p)
+		}
+	}
+	return nn.with(kept...)
+}
+
+// without returns the set less the paths assigned to and those through them.
+func (this nonNil) without(assigned map[string]bool) nonNil {
+	nn := make(nonNil, len(this))
+	for p := range this {
+		names := strings.Split(p, ".")
+		kept := true
+		for i := range names {
+			kept = kept && !assigned[strings.Join(names[:i+1], ".")]
+		}
+		if kept {
+			nn[p] = true
+		}
+	}
+	return nn
+}
+
+// checkOptionals checks that a value of an optional type is known not to be
+// nil wherever it's used in a way that needs a value: getting a member of it,
+// indexing it or calling it. It's known not to be nil in the body of an if
+// that checks it isn't, in the is cases of an if on it that can't match nil,
+// and after an if that checks it is and always leaves, until it's assigned.
+// A local that an anonymous function assigns is never known not to be nil.
+// nil itself can only be used as an optional or an interface.
+func (this *checker) checkOptionals() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			switch s := s.(type) {
+			case *ast.FunctionDef:
+				env := &typeEnv{constEnv: constEnv{file: f, class: c, locals: localNames(s)}, vars: localTypes(s)}
+				this.shared = make(map[string]bool)
+				ast.Inspect(s, func(n ast.General) bool {
+					if fn, ok := n.(*ast.FunctionDef); ok {
+						for _, c := range this.info.Captures[fn] {
+							this.shared[c.Name] = this.shared[c.Name] || c.Mutated
+						}
+					}
+					return true
+				})
+				this.inferTypes(env, s)
+				this.optStmts(env, s.Stmts(), nonNil{})
+				this.checkNils(env, s)
+			case *ast.PropertySet:
+				if !isConstSet(s) {
+					for i, p := range s.Props() {
+						this.checkNil(f, p.Type(), nthExpr(s.Vals, i, len(s.Props())))
+					}
+				}
+			}
+		}
+	})
+}
+
+// inferTypes gives each local of fn declared once without a type the type of
+// the value it's declared with, where that's known.
+func (this *checker) inferTypes(env *typeEnv, fn *ast.FunctionDef) {
+	decls := make(map[string]int)
+	ast.Inspect(fn, func(n ast.General) bool {
+		switch n := n.(type) {
+		case *ast.VarSetLine:
+			for _, v := range n.Vars() {
+				decls[v.Name()]++
+			}
+		case *ast.For:
+			for _, v := range n.Vars() {
+				decls[v]++
+			}
+		}
+		return true
+	})
+	ast.Inspect(fn, func(n ast.General) bool {
+		if line, ok := n.(*ast.VarSetLine); ok {
+			for i, v := range line.Vars() {
+				if v.Type() == nil && decls[v.Name()] == 1 {
+					if val := nthExpr(line.Vals, i, len(line.Vars())); val != nil {
+						env.vars[v.Name()] = this.typeOf(env, val)
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+func (this *checker) optStmts(env *typeEnv, stmts []ast.Statement, nn nonNil) {
+	for _, s := range stmts {
+		nn = this.optStmt(env, s, nn)
+	}
+}
+
+// optStmt checks s, where the values in nn are known not to be nil, and
+// returns those known not to be nil after it.
+func (this *checker) optStmt(env *typeEnv, s ast.Statement, nn nonNil) nonNil {
+	switch s := s.(type) {
+	case *ast.If:
+		if s.With != nil {
+			nn = this.optStmt(env, s.With, nn)
+		}
+		this.optExpr(env, s.Condition, nn)
+		cases := isCases(s)
+		if len(cases) == 0 {
+			this.optStmts(env, s.Stmts(), this.known(nn, nonNilIf(s.Condition, true)...))
+			if leaves(s.Stmts()) {
+				return this.known(nn, nonNilIf(s.Condition, false)...)
+			}
+		} else {
+			this.optCases(env, s, cases, nn)
+		}
+		return nn.without(assigns(s))
+	case *ast.For:
+		this.optExpr(env, s.In, nn)
+		nn = nn.without(assigns(s))
+		this.optStmts(env, s.Stmts(), nn)
+		return nn
+	case *ast.Loop:
+		nn = nn.without(assigns(s))
+		this.optStmts(env, s.Stmts(), nn)
+		return nn
+	case *ast.Select:
+		for _, st := range s.Stmts() {
+			if sc, ok := st.(*ast.SelectCase); ok {
+				this.optStmts(env, sc.Stmts(), this.optStmt(env, sc.Comm, nn))
+			}
+		}
+		return nn.without(assigns(s))
+	case *ast.Assign:
+		this.optExpr(env, s.Left, nn)
+		this.optExpr(env, s.Right, nn)
+		targets, vals := exprsOf(s.Left), exprsOf(s.Right)
+		for i, t := range targets {
+			path := pathOf(t)
+			if path == "" {
+				continue
+			}
+			nn = nn.without(map[string]bool{path: true})
+			if s.Op == token.ASSIGN && len(vals) == len(targets) && this.holdsValue(env, vals[i]) {
+				nn = this.known(nn, path)
+			}
+		}
+		return nn
+	case *ast.VarSet:
+		this.optExpr(env, s, nn)
+		return nn.without(assigns(s))
+	}
+	this.optExpr(env, s, nn)
+	return nn
+}
+
+// optCases checks the is cases of s. A case of a chain without a condition
+// knows what its own condition and the failed conditions before it show isn't
+// nil, and a case of an if on a value knows the value isn't nil unless the
+// case can match nil.
+func (this *checker) optCases(env *typeEnv, s *ast.If, cases []*ast.Is, nn nonNil) {
+	subject := ""
+	if s.Condition != nil {
+		subject = pathOf(s.Condition)
+	}
+	var failed []string
+	matchedNil := false
+	for _, is := range cases {
+		inner := this.known(nn, failed...)
+		conds := exprsOf(is.Condition)
+		switch {
+		case s.Condition == nil && len(conds) == 1:
+			this.optExpr(env, conds[0], inner)
+			failed = append(failed, nonNilIf(conds[0], false)...)
+			inner = this.known(inner, nonNilIf(conds[0], true)...)
+		case subject != "":
+			hasNil := false
+			for _, c := range conds {
+				_, isNil := c.(*ast.Nil)
+				hasNil = hasNil || isNil
+			}
+			if isDefault(is) {
+				hasNil = !matchedNil
+			}
+			matchedNil = matchedNil || hasNil
+			if !hasNil {
+				inner = this.known(inner, subject)
+			}
+		}
+		this.optStmts(env, is.Stmts(), inner)
+	}
+}
+
+// optExpr checks the uses of optional values in e, where the values in nn are
+// known not to be nil. The right operand of and and or also knows what the
+// left operand shows. An anonymous function may run at any time, so nothing
+// is known in it.
+func (this *checker) optExpr(env *typeEnv, e ast.General, nn nonNil) {
+	ast.Inspect(e, func(n ast.General) bool {
+		switch n := n.(type) {
+		case *ast.FunctionDef:
+			this.optStmts(env, n.Stmts(), nonNil{})
+			return false
+		case *ast.Binary:
+			switch n.Op {
+			case token.AND, token.OR:
+				this.optExpr(env, n.Left, nn)
+				this.optExpr(env, n.Right, this.known(nn, nonNilIf(n.Left, n.Op == token.AND)...))
+				return false
+			case token.DOT:
+				this.optExpr(env, n.Left, nn)
+				this.checkMembers(env, n)
+				switch r := n.Right.(type) {
+				case *ast.FunctionCall:
+					this.optExpr(env, r.Params, nn)
+				case *ast.Accessor:
+					this.optExpr(env, r.Index, nn)
+				}
+				return false
+			}
+		case *ast.Identifier:
+			this.checkDeref(env, n, nn)
+		case *ast.FunctionCall:
+			this.checkMayBeNil(env, n.Function, nn, "call")
+		case *ast.Accessor:
+			this.checkMayBeNil(env, n.Object, nn, "index")
+		case *ast.AccessorRange:
+			this.checkMayBeNil(env, n.Object, nn, "slice")
+		}
+		return true
+	})
+}
+
+// checkDeref reports getting a member of a value in the path id that may be
+// nil.
+func (this *checker) checkDeref(env *typeEnv, id *ast.Identifier, nn nonNil) {
+	names := identNames(id.Idents())
+	for i := 1; i < len(names); i++ {
+		if path := strings.Join(names[:i], "."); !nn[path] && this.isOptional(this.pathType(env, names[:i])) {
+			this.errorf(env.file, id.Pos, "cannot use %v, %v may be nil", strings.Join(names, "."), path)
+			return
+		}
+	}
+}
+
+// checkMembers reports getting a member of the left operand of b, or of one
+// of the properties b gets from it in turn, that may be nil. Only paths of
+// locals and properties can be known not to be nil, so any other optional
+// value may be.
+func (this *checker) checkMembers(env *typeEnv, b *ast.Binary) {
+	names, _ := memberNames(b.Right)
+	typ := this.typeOf(env, b.Left)
+	for i, name := range names {
+		if this.isOptional(typ) {
+			if i == 0 {
+				this.errorf(env.file, ast.PosOf(b.Right), "cannot use .%v of a %v, it may be nil", strings.Join(names, "."), typ)
+			} else {
+				this.errorf(env.file, ast.PosOf(b.Right), "cannot use .%v, .%v may be nil", strings.Join(names, "."), strings.Join(names[:i], "."))
+			}
+			return
+		}
+		typ = this.propertyType(typ, name)
+	}
+}
+
+// checkMayBeNil reports doing something with e, which needs it to hold a
+// value, if it may be nil.
+func (this *checker) checkMayBeNil(env *typeEnv, e ast.Expression, nn nonNil, do string) {
+	id, ok := e.(*ast.Identifier)
+	if !ok {
+		if typ := this.typeOf(env, e); this.isOptional(typ) {
+			this.errorf(env.file, ast.PosOf(e), "cannot %v a %v, it may be nil", do, typ)
+		}
+		return
+	}
+	names := identNames(id.Idents())
+	if path := strings.Join(names, "."); !nn[path] && this.isOptional(this.pathType(env, names)) {
+		this.errorf(env.file, id.Pos, "cannot %v %v, it may be nil", do, path)
+	}
+}
+
+// pathType returns the type of the local or property at the path names, or
+// nil if it isn't known.
+func (this *checker) pathType(env *typeEnv, names []string) ast.Statement {
+	var typ ast.Statement
+	if t, ok := env.vars[names[0]]; ok {
+		typ = t
+	} else if env.locals[names[0]] {
+		return nil
+	} else if m := this.info.Member(env.class, names[0]); m != nil && m.Sig == nil {
+		typ = m.Type
+	}
+	for _, name := range names[1:] {
+		typ = this.propertyType(typ, name)
+	}
+	return typ
+}
+
+// propertyType returns the type of the instance property name of a value of
+// type typ, or of the value it holds if it's optional, or nil if it isn't
+// known.
+func (this *checker) propertyType(typ ast.Statement, name string) ast.Statement {
+	if opt, ok := this.resolveType(typ).(*ast.Optional); ok {
+		typ = opt.Type
+	}
+	t, ok := this.resolveType(typ).(*ast.TypeIdent)
+	if !ok {
+		return nil
+	}
+	c, ok := this.typeDecl(t).(*ast.Class)
+	if !ok {
+		return nil
+	}
+	members := this.info.Members[c]
+	if inst := this.info.Instance(t.String()); inst != nil {
+		members = inst.Members
+	}
+	for _, m := range members {
+		if m.Name == name && m.Sig == nil && !m.Static {
+			return m.Type
+		}
+	}
+	return nil
+}
+
+func (this *checker) isOptional(typ ast.Statement) bool {
+	_, ok := this.resolveType(typ).(*ast.Optional)
+	return ok
+}
+
+// holdsValue returns whether e is known not to be nil.
+func (this *checker) holdsValue(env *typeEnv, e ast.Expression) bool {
+	if _, ok := e.(*ast.Nil); ok {
+		return false
+	}
+	typ := this.typeOf(env, e)
+	return typ != nil && !this.isOptional(typ)
+}
+
+// nonNilIf returns the values that cond being when shows aren't nil.
+func nonNilIf(cond ast.Expression, when bool) []string {
+	switch c := cond.(type) {
+	case *ast.ExprList:
+		if exprs := c.Exprs(); len(exprs) == 1 {
+			return nonNilIf(exprs[0], when)
+		}
+	case *ast.Unary:
+		if c.Op == token.NOT {
+			return nonNilIf(c.Expr, !when)
+		}
+	case *ast.Binary:
+		switch c.Op {
+		case token.EQUAL, token.NOT_EQUAL:
+			if (c.Op == token.NOT_EQUAL) != when {
+				return nil
+			}
+			if _, ok := c.Right.(*ast.Nil); ok && pathOf(c.Left) != "" {
+				return []string{pathOf(c.Left)}
+			}
+			if _, ok := c.Left.(*ast.Nil); ok && pathOf(c.Right) != "" {
+				return []string{pathOf(c.Right)}
+			}
+		case token.AND:
+			if when {
+				return append(nonNilIf(c.Left, true), nonNilIf(c.Right, true)...)
+			}
+		case token.OR:
+			if !when {
+				return append(nonNilIf(c.Left, false), nonNilIf(c.Right, false)...)
+			}
+		}
+	}
+	return nil
+}
+
+// pathOf returns the path that e names, or "" if it isn't an identifier.
+func pathOf(e ast.Expression) string {
+	if id, ok := e.(*ast.Identifier); ok {
+		return strings.Join(identNames(id.Idents()), ".")
+	}
+	return ""
+}
+
+// leaves returns whether stmts always end by leaving the function or loop.
+func leaves(stmts []ast.Statement) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	switch stmts[len(stmts)-1].(type) {
+	case *ast.Return, *ast.Break:
+		return true
+	}
+	return false
+}
+
+// assigns returns the paths assigned to and the variables declared in n.
+func assigns(n ast.General) map[string]bool {
+	paths := make(map[string]bool)
+	ast.Inspect(n, func(m ast.General) bool {
+		switch m := m.(type) {
+		case *ast.Assign:
+			for _, t := range exprsOf(m.Left) {
+				if path := pathOf(t); path != "" {
+					paths[path] = true
+				}
+			}
+		case *ast.VarSetLine:
+			for _, v := range m.Vars() {
+				paths[v.Name()] = true
+			}
+		}
+		return true
+	})
+	return paths
+}
+
+// checkNils checks every nil in fn used as a value of a known type: in a var
+// line, an assignment, a ret or as an argument to a function of the package.
+func (this *checker) checkNils(env *typeEnv, fn *ast.FunctionDef) {
+	ast.Inspect(fn, func(n ast.General) bool {
+		switch n := n.(type) {
+		case *ast.FunctionDef:
+			if n != fn {
+				this.checkNils(env, n)
+				return false
+			}
+		case *ast.VarSetLine:
+			for i, v := range n.Vars() {
+				this.checkNil(env.file, v.Type(), nthExpr(n.Vals, i, len(n.Vars())))
+			}
+		case *ast.Assign:
+			targets := exprsOf(n.Left)
+			for i, t := range targets {
+				this.checkNil(env.file, this.typeOf(env, t), nthExpr(n.Right, i, len(targets)))
+			}
+		case *ast.Return:
+			for i, r := range fn.Returns() {
+				this.checkNil(env.file, r, nthExpr(n.Vals, i, len(fn.Returns())))
+			}
+		case *ast.FunctionCall:
+			if _, sig := this.calledFunc(&env.constEnv, n); sig != nil && n.Params != nil {
+				for i, p := range sig.Params() {
+					this.checkNil(env.file, p, nthExpr(n.Params, i, len(sig.Params())))
+				}
+			}
+		}
+		return true
+	})
+}
+
+// checkNil reports e if it's nil but typ can't hold nil.
+func (this *checker) checkNil(f *ast.File, typ ast.Statement, e ast.Expression) {
+	if _, ok := e.(*ast.Nil); !ok || typ == nil {
+		return
+	}
+	switch this.resolveType(typ).(type) {
+	case *ast.Optional:
+		return
+	case *ast.Array, *ast.FunctionSig:
+	default:
+		if _, ok := this.typeDecl(typ).(*ast.Class); !ok && this.basicTypeName(typ) == "" {
+			return
+		}
+	}
+	this.errorf(f, ast.PosOf(e), "cannot use nil as %v, it isn't optional", typ)
+}
@@ -0,0 +1,102 @@
+package checker
+
+import (
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/constant"
+	"github.com/defiant00/char/compiler/token"
+)
+
+// checkPatterns checks the patterns of every is case and records the
+// variables each case binds, which are only in scope in that case's body.
+func (this *checker) checkPatterns() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			if fn, ok := s.(*ast.FunctionDef); ok {
+				ast.Inspect(fn, func(n ast.General) bool {
+					if s, ok := n.(*ast.If); ok {
+						for _, is := range isCases(s) {
+							this.checkCase(f, s, is)
+						}
+					}
+					return true
+				})
+			}
+		}
+	})
+}
+
+func (this *checker) checkCase(f *ast.File, s *ast.If, is *ast.Is) {
+	el, ok := is.Condition.(*ast.ExprList)
+	if !ok {
+		return
+	}
+	var binds []string
+	seen := make(map[string]bool)
+	bind := func(pos token.Position, name string) {
+		if seen[name] {
+			this.errorf(f, pos, "%v is bound more than once in the same is case", name)
+			return
+		}
+		seen[name] = true
+		binds = append(binds, name)
+	}
+	for _, e := range el.Exprs() {
+		if isPattern(e) && s.Condition == nil {
+			this.errorf(f, ast.PosOf(e), "pattern needs a value to match, as in if x")
+			continue
+		}
+		this.checkPattern(f, e, bind)
+	}
+	if len(binds) > 0 && len(el.Exprs()) > 1 {
+		this.errorf(f, is.Pos, "cannot bind variables in an is case with more than one pattern")
+	}
+	if len(binds) > 0 {
+		this.info.Binds[is] = binds
+	}
+}
+
+// checkPattern checks pat, calling bind for every variable it binds.
+func (this *checker) checkPattern(f *ast.File, pat ast.Expression, bind func(token.Position, string)) {
+	switch p := pat.(type) {
+	case *ast.RangePattern:
+		low, okLow := this.info.Values[p.Low]
+		high, okHigh := this.info.Values[p.High]
+		if okLow && okHigh {
+			if _, err := constant.BinaryOp(low, token.RIGHT_CARET, high); err != nil {
+				this.errorf(f, p.Pos, "invalid range %v to %v: %v", low, high, err)
+			} else if compares(low, token.RIGHT_CARET, high) {
+				this.errorf(f, p.Pos, "empty range %v to %v", low, high)
+			}
+		}
+	case *ast.TypePattern:
+		this.lookupDecl(f, p.Type)
+		bind(p.Pos, p.Name)
+	case *ast.ShapePattern:
+		decl, ok := this.lookupDecl(f, p.Type)
+		c, isClass := decl.(*ast.Class)
+		if ok && !isClass {
+			this.errorf(f, p.Pos, "%v is not a class", p.Type)
+		}
+		for _, field := range p.Fields() {
+			if isClass {
+				if m := this.info.Member(c, field.Key); m == nil || m.Sig != nil {
+					this.errorf(f, field.Pos, "%v has no property %v", c.Name, field.Key)
+				}
+			}
+			if field.Val == nil {
+				bind(field.Pos, field.Key)
+			} else {
+				this.checkPattern(f, field.Val, bind)
+			}
+		}
+	}
+}
+
+// isPattern returns whether e is more than a value to compare against.
+func isPattern(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.RangePattern, *ast.TypePattern, *ast.ShapePattern:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/prelude"
+	"sync"
+)
+
+var (
+	preludeOnce    sync.Once
+	preludeChecker *checker
+)
+
+// checkedPrelude returns the checker of the prelude, checking it the first
+// time. The prelude ships with the compiler, so any error in it is a bug.
+func checkedPrelude() *checker {
+	preludeOnce.Do(func() {
+		pkg, errs := ast.NewPackage(prelude.Name, prelude.Files())
+		c := newChecker(pkg)
+		c.check()
+		if errs = append(errs, c.errs...); len(errs) > 0 {
+			panic(fmt.Sprint("prelude: ", errs))
+		}
+		preludeChecker = c
+	})
+	return preludeChecker
+}
+
+// usePrelude puts the prelude's scope around the package's, so its
+// declarations can be used anywhere the package doesn't declare the same
+// name, and starts from everything checking the prelude worked out.
+func (this *checker) usePrelude() {
+	p := checkedPrelude()
+	this.pkg.Scope.Outer = p.pkg.Scope
+	for k, v := range p.info.Consts {
+		this.info.Consts[k] = v
+	}
+	for k, v := range p.info.Members {
+		this.info.Members[k] = v
+	}
+	for k, v := range p.info.Methods {
+		this.info.Methods[k] = v
+	}
+	for k, v := range p.info.Aliases {
+		this.info.Aliases[k] = v
+	}
+	for k, v := range p.fileOf {
+		this.fileOf[k] = v
+	}
+	for k, v := range p.aliasing {
+		this.aliasing[k] = v
+	}
+	for name, v := range p.aliases {
+		if this.pkg.Scope.Lookup(name) == p.pkg.Scope.Lookup(name) { // not hidden
+			this.aliases[name] = v
+		}
+	}
+	for k, v := range p.consts {
+		this.consts[k] = v
+	}
+	for k, v := range p.mixing {
+		this.mixing[k] = v
+	}
+	for k, v := range p.intfs {
+		this.intfs[k] = v
+	}
+}
+
+// builtinClass is the prelude class whose static functions can be called
+// without naming it.
+const builtinClass = "Builtin"
+
+// preludeScope returns the scope of the prelude's declarations.
+func (this *checker) preludeScope() *ast.Scope {
+	if this.pkg.Name == prelude.Name {
+		return this.pkg.Scope
+	}
+	return this.pkg.Scope.Outer
+}
+
+// isPreludeName returns whether name refers to the prelude package: either
+// its own name or a name it is used as, where the package doesn't declare
+// the name itself.
+func (this *checker) isPreludeName(name string) bool {
+	if this.pkg.Scope.Lookup(name) != nil {
+		return false
+	}
+	if pack, ok := this.uses[name]; ok {
+		return pack == prelude.Name
+	}
+	return name == prelude.Name
+}
+
+// builtin returns the static function name of the prelude's Builtin class,
+// or nil if there isn't one.
+func (this *checker) builtin(name string) *Member {
+	obj := this.preludeScope().Lookup(builtinClass)
+	if obj == nil {
+		return nil
+	}
+	c, ok := obj.Decl.(*ast.Class)
+	if !ok {
+		return nil
+	}
+	if m := this.info.Member(c, name); m != nil && m.Static && m.Sig != nil {
+		return m
+	}
+	return nil
+}
+
+// checkNatives reports native functions outside of the prelude, as only the
+// compiler's runtime can implement them. The runtime also generates the
+// functions of enums.
+func (this *checker) checkNatives() {
+	if this.pkg.Name == prelude.Name {
+		return
+	}
+	this.classes(func(f *ast.File, c *ast.Class) {
+		if c.Enum {
+			return
+		}
+		for _, s := range c.Stmts() {
+			if fn, ok := s.(*ast.FunctionDef); ok && fn.Native {
+				this.errorf(f, fn.Pos, "native function %v can only be declared in the prelude", fn.Name)
+			}
+		}
+	})
+}
+
+// preludeTypeArgs returns the type arguments of typ if it is an instance of
+// the generic prelude class name with n type parameters, or nil.
+func (this *checker) preludeTypeArgs(typ ast.Statement, name string, n int) []ast.Statement {
+	t, ok := this.resolveType(typ).(*ast.TypeIdent)
+	if !ok || len(t.TypeParams()) != n {
+		return nil
+	}
+	obj := this.preludeScope().Lookup(name)
+	if decl, _ := this.resolveDecl(t); obj == nil || decl != obj.Decl {
+		return nil
+	}
+	return t.TypeParams()
+}
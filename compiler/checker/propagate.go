@@ -0,0 +1,123 @@
+package checker
+
+import "github.com/defiant00/char/compiler/ast"
+
+// errorIntf is the prelude interface that every error implements.
+const errorIntf = "Error"
+
+// checkPropagation checks every use of ?. It must follow a call whose last
+// result is an Error, in a function whose last result is Error, and can't be
+// in a deferred call as that runs once the function is already returning.
+func (this *checker) checkPropagation() {
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			if fn, ok := s.(*ast.FunctionDef); ok {
+				env := &constEnv{file: f, class: c, locals: localNames(fn)}
+				this.checkPropagates(env, fn, fn, false)
+			}
+		}
+	})
+}
+
+// checkPropagates checks the uses of ? in n, which is part of fn. Anonymous
+// functions return on their own, so the ? in them are checked against them.
+func (this *checker) checkPropagates(env *constEnv, fn *ast.FunctionDef, n ast.General, deferred bool) {
+	ast.Inspect(n, func(m ast.General) bool {
+		switch m := m.(type) {
+		case *ast.FunctionDef:
+			if m != n {
+				this.checkPropagates(env, m, m, false)
+				return false
+			}
+		case *ast.Defer:
+			this.checkPropagates(env, fn, m.Expr, true)
+			return false
+		case *ast.Propagate:
+			this.checkPropagate(env, fn, m, deferred)
+		}
+		return true
+	})
+}
+
+func (this *checker) checkPropagate(env *constEnv, fn *ast.FunctionDef, p *ast.Propagate, deferred bool) {
+	if deferred {
+		this.errorf(env.file, p.Pos, "cannot use ? in a deferred call, it runs after the function returns")
+		return
+	}
+	if _, ok := p.Expr.(*ast.FunctionCall); !ok {
+		this.errorf(env.file, p.Pos, "? can only follow a call")
+		return
+	}
+	rets := fn.Returns()
+	if len(rets) == 0 || !this.isError(rets[len(rets)-1], true) {
+		this.errorf(env.file, p.Pos, "cannot use ? in %v, its last result isn't Error", funcName(fn))
+	}
+	if name, sig := this.calledFunc(env, p.Expr); sig != nil {
+		if rets := sig.Returns(); len(rets) == 0 || !this.isError(rets[len(rets)-1], false) {
+			this.errorf(env.file, p.Pos, "cannot use ? on %v(), its last result isn't an Error", name)
+		}
+	}
+}
+
+// isError returns whether typ is the prelude's Error or, unless exact, a
+// class that implements it. Types from other packages might be either, so
+// they are assumed to be.
+func (this *checker) isError(typ ast.Statement, exact bool) bool {
+	var intf *ast.Interface
+	if obj := this.preludeScope().Lookup(errorIntf); obj != nil {
+		intf, _ = obj.Decl.(*ast.Interface)
+	}
+	switch d := this.typeDecl(typ).(type) {
+	case *ast.Interface:
+		return d == intf
+	case *ast.Class:
+		return !exact && intf != nil && len(this.implements(d, intf)) == 0
+	}
+	_, isIdent := this.resolveType(typ).(*ast.TypeIdent)
+	return isIdent && this.basicTypeName(typ) == ""
+}
+
+// propagated returns the signature of the call that p propagates the error
+// of, less its Error result, or nil if it isn't known.
+func (this *checker) propagated(env *constEnv, p *ast.Propagate) (string, *ast.IntfFuncSig) {
+	name, sig := this.calledFunc(env, p.Expr)
+	if sig == nil || len(sig.Returns()) == 0 || !this.isError(sig.Returns()[len(sig.Returns())-1], false) {
+		return "", nil
+	}
+	rest := &ast.IntfFuncSig{Pos: sig.Pos, Name: sig.Name}
+	for _, p := range sig.Params() {
+		rest.AddParam(p)
+	}
+	for _, r := range sig.Returns()[:len(sig.Returns())-1] {
+		rest.AddReturn(r)
+	}
+	return name, rest
+}
+
+// mayPropagate returns whether s itself, leaving out the statements in its
+// body, uses ? and so can return early.
+func mayPropagate(s ast.Statement) bool {
+	var exprs []ast.General
+	switch s := s.(type) {
+	case *ast.If:
+		exprs = []ast.General{s.Condition, s.With}
+	case *ast.For:
+		exprs = []ast.General{s.In}
+	case *ast.Is, *ast.Loop, *ast.Defer:
+	default:
+		exprs = []ast.General{s}
+	}
+	found := false
+	for _, e := range exprs {
+		ast.Inspect(e, func(n ast.General) bool {
+			switch n.(type) {
+			case *ast.Propagate:
+				found = true
+			case *ast.FunctionDef:
+				return false
+			}
+			return !found
+		})
+	}
+	return found
+}
@@ -0,0 +1,128 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/constant"
+)
+
+// basicType is a built-in type that constants can have.
+type basicType struct {
+	kind     constant.Kind
+	bits     int
+	unsigned bool
+}
+
+var basicTypes = map[string]basicType{
+	"bool":    {kind: constant.Bool},
+	"string":  {kind: constant.String},
+	"char":    {kind: constant.Int, bits: 32},
+	"int":     {kind: constant.Int, bits: 64},
+	"int8":    {kind: constant.Int, bits: 8},
+	"int16":   {kind: constant.Int, bits: 16},
+	"int32":   {kind: constant.Int, bits: 32},
+	"int64":   {kind: constant.Int, bits: 64},
+	"uint":    {kind: constant.Int, bits: 64, unsigned: true},
+	"uint8":   {kind: constant.Int, bits: 8, unsigned: true},
+	"uint16":  {kind: constant.Int, bits: 16, unsigned: true},
+	"uint32":  {kind: constant.Int, bits: 32, unsigned: true},
+	"uint64":  {kind: constant.Int, bits: 64, unsigned: true},
+	"float":   {kind: constant.Float, bits: 64},
+	"float32": {kind: constant.Float, bits: 32},
+	"float64": {kind: constant.Float, bits: 64},
+}
+
+// basicTypeName returns the name of typ if it is a basic type or an alias of
+// one, or "".
+func (this *checker) basicTypeName(typ ast.Statement) string {
+	if t, ok := this.resolveType(typ).(*ast.TypeIdent); ok {
+		if _, ok := basicTypes[t.String()]; ok {
+			return t.String()
+		}
+	}
+	return ""
+}
+
+// convertConst converts v to the basic type name, reporting whether it
+// can't be represented by it.
+func convertConst(v constant.Value, name string) (constant.Value, error) {
+	if v.Kind() == constant.Unknown {
+		return v, nil
+	}
+	b := basicTypes[name]
+	switch b.kind {
+	case constant.Int:
+		v = constant.ToInt(v)
+		if v.Kind() == constant.Float {
+			return constant.MakeUnknown(), fmt.Errorf("constant %v truncated to %v", v, name)
+		}
+		if v.Kind() == constant.Int && !constant.IntFits(v, b.bits, b.unsigned) {
+			return constant.MakeUnknown(), fmt.Errorf("constant %v overflows %v", v, name)
+		}
+	case constant.Float:
+		v = constant.ToFloat(v)
+		if v.Kind() == constant.Float && !constant.FloatFits(v, b.bits) {
+			return constant.MakeUnknown(), fmt.Errorf("constant %v overflows %v", v, name)
+		}
+	}
+	if v.Kind() != b.kind {
+		return constant.MakeUnknown(), fmt.Errorf("cannot use %v as %v", v, name)
+	}
+	return v, nil
+}
+
+// identical returns whether two types written in the source are the same
+// type once their aliases are resolved. Missing types are only identical to
+// each other.
+func (this *checker) identical(a, b ast.Statement) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return fmt.Sprint(this.resolveType(a)) == fmt.Sprint(this.resolveType(b))
+}
+
+// paramTypes returns the type of every parameter of fn. Like Go, a
+// parameter without a type has the type of the next one that has one, so
+// add(a, b int) takes two ints.
+func paramTypes(fn *ast.FunctionDef) []ast.Statement {
+	params := fn.Params()
+	types := make([]ast.Statement, len(params))
+	var typ ast.Statement
+	for i := len(params) - 1; i >= 0; i-- {
+		if params[i].Type() != nil {
+			typ = params[i].Type()
+		}
+		types[i] = typ
+	}
+	return types
+}
+
+// sigOf returns the signature of fn as an interface would declare it.
+func sigOf(fn *ast.FunctionDef) *ast.IntfFuncSig {
+	sig := &ast.IntfFuncSig{Pos: fn.Pos, Name: fn.Name}
+	for _, t := range paramTypes(fn) {
+		sig.AddParam(t)
+	}
+	for _, r := range fn.Returns() {
+		sig.AddReturn(r)
+	}
+	return sig
+}
+
+// identicalSigs returns whether two signatures take and return the same types.
+func (this *checker) identicalSigs(a, b *ast.IntfFuncSig) bool {
+	if len(a.Params()) != len(b.Params()) || len(a.Returns()) != len(b.Returns()) {
+		return false
+	}
+	for i, p := range a.Params() {
+		if !this.identical(p, b.Params()[i]) {
+			return false
+		}
+	}
+	for i, r := range a.Returns() {
+		if !this.identical(r, b.Returns()[i]) {
+			return false
+		}
+	}
+	return true
+}
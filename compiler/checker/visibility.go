@@ -0,0 +1,193 @@
+package checker
+
+import (
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/prelude"
+	"github.com/defiant00/char/compiler/token"
+	"strings"
+)
+
+// checkVisibility reports uses of declarations and members that are private
+// to another package. Everything in a package is visible throughout it, while
+// only what isn't marked priv is visible to other packages. The prelude is the
+// only other package whose declarations the checker sees.
+func (this *checker) checkVisibility() {
+	if this.pkg.Name == prelude.Name {
+		return
+	}
+	for _, f := range this.pkg.Files() {
+		ast.Inspect(f, func(n ast.General) bool {
+			if t, ok := n.(*ast.TypeIdent); ok {
+				this.checkVisibleDecl(f, t.Pos, t.Idents())
+			}
+			return true
+		})
+	}
+
+	this.classes(func(f *ast.File, c *ast.Class) {
+		for _, s := range c.Stmts() {
+			if fn, ok := s.(*ast.FunctionDef); ok {
+				env := &typeEnv{constEnv: constEnv{file: f, class: c, locals: localNames(fn)}, vars: localTypes(fn)}
+				this.inferTypes(env, fn)
+				var visit func(n ast.General) bool
+				visit = func(n ast.General) bool {
+					switch n := n.(type) {
+					case *ast.Identifier:
+						this.checkVisibleIdent(env, n)
+					case *ast.Constructor:
+						this.checkVisibleFields(env, n)
+					case *ast.Binary:
+						if n.Op != token.DOT {
+							break
+						}
+						// The names after the . are members, not
+						// declarations or locals.
+						ast.Inspect(n.Left, visit)
+						this.checkVisibleMembers(env, n)
+						switch r := n.Right.(type) {
+						case *ast.FunctionCall:
+							ast.Inspect(r.Params, visit)
+						case *ast.Accessor:
+							ast.Inspect(r.Index, visit)
+						}
+						return false
+					}
+					return true
+				}
+				ast.Inspect(fn, visit)
+			}
+		}
+	})
+}
+
+// checkVisibleDecl checks the declaration that names refers to, and the member
+// of it that the name after it refers to, if any.
+func (this *checker) checkVisibleDecl(f *ast.File, pos token.Position, names []string) {
+	obj, rest := this.lookupName(names)
+	if obj == nil {
+		if len(names) == 1 {
+			if m := this.builtin(names[0]); m != nil && this.foreign(m.From) && isPrivate(m.Decl) {
+				this.errorf(f, pos, "%v is private to package %v", names[0], prelude.Name)
+			}
+		}
+		return
+	}
+	if !this.foreign(obj.Decl) {
+		return
+	}
+	name := strings.Join(names[:len(names)-len(rest)], ".")
+	if isPrivate(obj.Decl) {
+		this.errorf(f, pos, "%v is private to package %v", name, prelude.Name)
+		return
+	}
+	if c, ok := obj.Decl.(*ast.Class); ok && len(rest) > 0 && this.privateMember(c, rest[0]) {
+		this.errorf(f, pos, "%v.%v is private to package %v", name, rest[0], prelude.Name)
+	}
+}
+
+// checkVisibleIdent checks the declaration id refers to, or the members of the
+// values along its path if it starts with a local or a member.
+func (this *checker) checkVisibleIdent(env *typeEnv, id *ast.Identifier) {
+	names := identNames(id.Idents())
+	if _, isVar := env.vars[names[0]]; !isVar && !env.locals[names[0]] && this.info.Member(env.class, names[0]) == nil {
+		this.checkVisibleDecl(env.file, id.Pos, names)
+		return
+	}
+	for i := 1; i < len(names); i++ {
+		typ := this.pathType(env, names[:i])
+		if opt, ok := this.resolveType(typ).(*ast.Optional); ok {
+			typ = opt.Type
+		}
+		c, ok := this.typeDecl(typ).(*ast.Class)
+		if !ok {
+			return
+		}
+		if this.foreign(c) && this.privateMember(c, names[i]) {
+			this.errorf(env.file, id.Pos, "%v.%v is private to package %v", c.Name, names[i], prelude.Name)
+			return
+		}
+	}
+}
+
+// checkVisibleMembers checks the members that b gets in turn from the value
+// of its left operand.
+func (this *checker) checkVisibleMembers(env *typeEnv, b *ast.Binary) {
+	names, _ := memberNames(b.Right)
+	typ := this.typeOf(env, b.Left)
+	for _, name := range names {
+		if opt, ok := this.resolveType(typ).(*ast.Optional); ok {
+			typ = opt.Type
+		}
+		c, ok := this.typeDecl(typ).(*ast.Class)
+		if !ok {
+			return
+		}
+		if this.foreign(c) && this.privateMember(c, name) {
+			this.errorf(env.file, ast.PosOf(b.Right), "%v.%v is private to package %v", c.Name, name, prelude.Name)
+			return
+		}
+		typ = this.propertyType(typ, name)
+	}
+}
+
+// checkVisibleFields checks the properties that a constructor sets.
+func (this *checker) checkVisibleFields(env *typeEnv, cons *ast.Constructor) {
+	id, ok := cons.Type.(*ast.Identifier)
+	if !ok {
+		return
+	}
+	c := this.lookupClass(identNames(id.Idents())...)
+	if c == nil || !this.foreign(c) {
+		return
+	}
+	for _, p := range cons.Params() {
+		if kv, ok := p.(*ast.KeyVal); ok && this.privateMember(c, kv.Key) {
+			this.errorf(env.file, kv.Pos, "cannot set %v.%v, it is private to package %v", c.Name, kv.Key, prelude.Name)
+		}
+	}
+}
+
+// foreign returns whether the top-level decl is from another package.
+func (this *checker) foreign(decl ast.Statement) bool {
+	return this.pkg.Name != prelude.Name && checkedPrelude().fileOf[decl] != nil
+}
+
+// privateMember returns whether the member or constant name of c is marked
+// priv where it's declared.
+func (this *checker) privateMember(c *ast.Class, name string) bool {
+	if m := this.info.Member(c, name); m != nil {
+		c = m.From
+	}
+	for _, s := range c.Stmts() {
+		switch s := s.(type) {
+		case *ast.PropertySet:
+			for _, p := range s.Props() {
+				if p.Name() == name {
+					return s.Private
+				}
+			}
+		case *ast.FunctionDef:
+			if s.Name == name {
+				return s.Private
+			}
+		}
+	}
+	return false
+}
+
+// isPrivate returns whether decl is marked priv.
+func isPrivate(decl ast.Statement) bool {
+	switch d := decl.(type) {
+	case *ast.Alias:
+		return d.Private
+	case *ast.Class:
+		return d.Private
+	case *ast.Interface:
+		return d.Private
+	case *ast.PropertySet:
+		return d.Private
+	case *ast.FunctionDef:
+		return d.Private
+	}
+	return false
+}
@@ -1,15 +1,26 @@
 package compiler
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/checker"
+	"github.com/defiant00/char/compiler/lexer"
 	"github.com/defiant00/char/compiler/parser"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
-func Build(path string, build, format, printTokens, printAST bool) {
-	fmt.Println("Building", path)
+// Build parses all .char files in path into a package and checks it, reporting
+// every parse error before any error found checking it. If asJSON is set,
+// nothing but a single JSON document is written to stdout; it contains the
+// tokens and/or AST of every file depending on printTokens and printAST.
+func Build(path string, build, format, printTokens, printAST, asJSON bool) {
+	if !asJSON {
+		fmt.Println("Building", path)
+	}
 
 	d, err := os.Open(path)
 	if err != nil {
@@ -17,19 +28,66 @@ func Build(path string, build, format, printTokens, printAST bool) {
 	}
 	defer d.Close()
 
-	files, err := d.Readdir(-1)
+	names, err := d.Readdirnames(-1)
 	if err != nil {
 		panic(err)
 	}
+	sort.Strings(names) // parse files in a stable order
 
-	for _, file := range files {
-		if file.Mode().IsRegular() && filepath.Ext(file.Name()) == ".char" {
-			fAST, _ := parser.Parse(filepath.Join(path, file.Name()), build, format, printTokens)
-			if printAST {
-				fmt.Println("\n\nAST")
-				ast.Print(fAST, 1)
-			}
+	var files []*ast.File
+	var jsonFiles []interface{}
+	var errs []error
+	for _, name := range names {
+		file := filepath.Join(path, name)
+		if fi, err := os.Stat(file); err != nil || !fi.Mode().IsRegular() || filepath.Ext(name) != ".char" {
+			continue
+		}
+		var fAST ast.General
+		if asJSON {
+			var jf map[string]interface{}
+			fAST, jf = parseJSON(file, build, printTokens, printAST)
+			jsonFiles = append(jsonFiles, jf)
+		} else {
+			fAST, _ = parser.Parse(file, build, format, printTokens)
+		}
+		errs = append(errs, ast.ParseErrors(file, fAST)...)
+		if f, ok := fAST.(*ast.File); ok {
+			files = append(files, f)
+		}
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		panic(err)
+	}
+	pkg, pkgErrs := ast.NewPackage(filepath.Base(abs), files)
+	_, checkErrs := checker.Check(pkg)
+	errs = append(append(errs, pkgErrs...), checkErrs...)
+
+	if asJSON {
+		errStrs := make([]string, 0, len(errs))
+		for _, e := range errs {
+			errStrs = append(errStrs, e.Error())
+		}
+		out, err := json.MarshalIndent(map[string]interface{}{
+			"version": ast.JSONVersion,
+			"package": pkg.Name,
+			"files":   jsonFiles,
+			"errors":  errStrs,
+		}, "", "  ")
+		if err != nil {
+			panic(err)
 		}
+		fmt.Println(string(out))
+		return
+	}
+
+	for _, e := range errs {
+		fmt.Println(e)
+	}
+	if printAST {
+		fmt.Println("\n\nAST")
+		ast.Print(pkg, 1)
 	}
 	/*
 		file := parse(input)
@@ -42,3 +100,27 @@ func Build(path string, build, format, printTokens, printAST bool) {
 		}
 	*/
 }
+
+// parseJSON lexes and parses a file without printing anything, returning its
+// AST and its JSON representation.
+func parseJSON(file string, build, printTokens, printAST bool) (ast.General, map[string]interface{}) {
+	dat, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(err)
+	}
+
+	jf := map[string]interface{}{"name": file}
+	tokens := lexer.LexAll(string(dat))
+	if printTokens {
+		jf["tokens"] = tokens
+	}
+
+	var fAST ast.General = &ast.File{Name: file}
+	if build {
+		fAST, _ = parser.ParseTokens(file, tokens)
+	}
+	if printAST {
+		jf["ast"] = ast.JSON(fAST)
+	}
+	return fAST, jf
+}
@@ -0,0 +1,362 @@
+// Package constant implements the values of compile time constants and the
+// operations on them. Integers are arbitrary precision and floats are exact
+// fractions, so no intermediate result of a constant expression can overflow
+// or lose precision; only storing a value in a sized type can.
+package constant
+
+import (
+	"fmt"
+	"github.com/defiant00/char/compiler/token"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of a Value.
+type Kind int
+
+const (
+	Unknown Kind = iota // the value couldn't be computed
+	Bool
+	String
+	Int
+	Float
+)
+
+// maxShift is the largest shift count allowed, to keep a stray << from
+// allocating huge numbers.
+const maxShift = 1 << 13
+
+// Value is a constant value. The zero Value is not valid; use MakeUnknown.
+type Value interface {
+	Kind() Kind
+	String() string
+}
+
+type unknownVal struct{}
+type boolVal bool
+type stringVal string
+type intVal struct{ val *big.Int }
+type floatVal struct{ val *big.Rat }
+
+func (this unknownVal) Kind() Kind { return Unknown }
+func (this boolVal) Kind() Kind    { return Bool }
+func (this stringVal) Kind() Kind  { return String }
+func (this intVal) Kind() Kind     { return Int }
+func (this floatVal) Kind() Kind   { return Float }
+
+func (this unknownVal) String() string { return "unknown" }
+func (this boolVal) String() string    { return strconv.FormatBool(bool(this)) }
+func (this stringVal) String() string  { return strconv.Quote(string(this)) }
+func (this intVal) String() string     { return this.val.String() }
+
+func (this floatVal) String() string {
+	f, _ := this.val.Float64()
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0" // keep whole floats distinguishable from ints
+	}
+	return s
+}
+
+// MakeUnknown returns the value of an expression that couldn't be computed.
+// Operations on it silently produce more unknown values, so only the original
+// problem is reported.
+func MakeUnknown() Value { return unknownVal{} }
+
+func MakeBool(b bool) Value { return boolVal(b) }
+
+func MakeString(s string) Value { return stringVal(s) }
+
+func MakeInt64(x int64) Value { return intVal{big.NewInt(x)} }
+
+func MakeFloat64(x float64) Value {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return MakeUnknown()
+	}
+	return floatVal{new(big.Rat).SetFloat64(x)}
+}
+
+// MakeFromLiteral returns the value of a literal token's text.
+func MakeFromLiteral(lit string, typ token.Type) (Value, error) {
+	switch typ {
+	case token.NUMBER:
+		if i, ok := new(big.Int).SetString(lit, 10); ok {
+			return intVal{i}, nil
+		}
+		if r, ok := new(big.Rat).SetString(lit); ok {
+			return floatVal{r}, nil
+		}
+		return MakeUnknown(), fmt.Errorf("invalid number literal %v", lit)
+	case token.STRING:
+		s, err := strconv.Unquote(`"` + lit + `"`)
+		if err != nil {
+			return MakeUnknown(), fmt.Errorf("invalid string literal \"%v\"", lit)
+		}
+		return MakeString(s), nil
+	case token.CHAR:
+		// Characters are integer code points, like runes in Go.
+		r, _, tail, err := strconv.UnquoteChar(lit, '\'')
+		if err != nil || tail != "" {
+			return MakeUnknown(), fmt.Errorf("invalid character literal '%v'", lit)
+		}
+		return MakeInt64(int64(r)), nil
+	case token.TRUE:
+		return MakeBool(true), nil
+	case token.FALSE:
+		return MakeBool(false), nil
+	}
+	return MakeUnknown(), fmt.Errorf("%v is not a literal", typ)
+}
+
+// BoolVal returns the value of a Bool.
+func BoolVal(x Value) (bool, bool) {
+	b, ok := x.(boolVal)
+	return bool(b), ok
+}
+
+// StringVal returns the value of a String.
+func StringVal(x Value) (string, bool) {
+	s, ok := x.(stringVal)
+	return string(s), ok
+}
+
+// Int64Val returns the value of an Int, and whether it fits in an int64.
+func Int64Val(x Value) (int64, bool) {
+	i, ok := x.(intVal)
+	if !ok || !i.val.IsInt64() {
+		return 0, false
+	}
+	return i.val.Int64(), true
+}
+
+// Float64Val returns the nearest float64 to a Float or Int, and whether it is
+// exact.
+func Float64Val(x Value) (float64, bool) {
+	f, ok := ToFloat(x).(floatVal)
+	if !ok {
+		return 0, false
+	}
+	return f.val.Float64()
+}
+
+// ToInt converts a Float with no fractional part to an Int. Any other value
+// is returned unchanged.
+func ToInt(x Value) Value {
+	if f, ok := x.(floatVal); ok && f.val.IsInt() {
+		return intVal{new(big.Int).Set(f.val.Num())}
+	}
+	return x
+}
+
+// ToFloat converts an Int to a Float. Any other value is returned unchanged.
+func ToFloat(x Value) Value {
+	if i, ok := x.(intVal); ok {
+		return floatVal{new(big.Rat).SetInt(i.val)}
+	}
+	return x
+}
+
+// IntFits returns whether the Int x fits in an integer of the given size.
+func IntFits(x Value, bits int, unsigned bool) bool {
+	i, ok := x.(intVal)
+	if !ok {
+		return false
+	}
+	if unsigned {
+		return i.val.Sign() >= 0 && i.val.BitLen() <= bits
+	}
+	if i.val.Sign() < 0 {
+		// -1 << (bits-1) is the smallest value, which is one more than the
+		// largest in magnitude.
+		return new(big.Int).Add(i.val, big.NewInt(1)).BitLen() <= bits-1
+	}
+	return i.val.BitLen() <= bits-1
+}
+
+// FloatFits returns whether the Float x is within the range of a 32 or 64 bit
+// float. Precision may still be lost when it is rounded.
+func FloatFits(x Value, bits int) bool {
+	f, ok := x.(floatVal)
+	if !ok {
+		return false
+	}
+	if bits == 32 {
+		v, _ := f.val.Float32()
+		return !math.IsInf(float64(v), 0)
+	}
+	v, _ := f.val.Float64()
+	return !math.IsInf(v, 0)
+}
+
+// UnaryOp returns the result of op x.
+func UnaryOp(op token.Type, x Value) (Value, error) {
+	switch x := x.(type) {
+	case unknownVal:
+		return x, nil
+	case boolVal:
+		if op == token.NOT {
+			return !x, nil
+		}
+	case intVal:
+		if op == token.SUB {
+			return intVal{new(big.Int).Neg(x.val)}, nil
+		}
+	case floatVal:
+		if op == token.SUB {
+			return floatVal{new(big.Rat).Neg(x.val)}, nil
+		}
+	}
+	return MakeUnknown(), fmt.Errorf("invalid operation: %v%v", op, x)
+}
+
+// BinaryOp returns the result of x op y. Both operands must be of the same
+// kind, except that an Int is converted to a Float when used with one. Shifts
+// are handled by Shift.
+func BinaryOp(x Value, op token.Type, y Value) (Value, error) {
+	if x.Kind() == Unknown || y.Kind() == Unknown {
+		return MakeUnknown(), nil
+	}
+	if op == token.LSHIFT || op == token.RSHIFT {
+		return Shift(x, op, y)
+	}
+	if x.Kind() == Float || y.Kind() == Float {
+		x, y = ToFloat(x), ToFloat(y)
+	}
+	if x.Kind() != y.Kind() {
+		return MakeUnknown(), fmt.Errorf("invalid operation: %v %v %v (mismatched kinds)", x, op, y)
+	}
+	if isComparison(op) {
+		return compare(x, op, y)
+	}
+
+	switch x := x.(type) {
+	case boolVal:
+		y := y.(boolVal)
+		switch op {
+		case token.AND:
+			return x && y, nil
+		case token.OR:
+			return x || y, nil
+		}
+	case stringVal:
+		if op == token.ADD {
+			return x + y.(stringVal), nil
+		}
+	case intVal:
+		a, b := x.val, y.(intVal).val
+		z := new(big.Int)
+		switch op {
+		case token.ADD:
+			return intVal{z.Add(a, b)}, nil
+		case token.SUB:
+			return intVal{z.Sub(a, b)}, nil
+		case token.MUL:
+			return intVal{z.Mul(a, b)}, nil
+		case token.DIV:
+			if b.Sign() == 0 {
+				return MakeUnknown(), fmt.Errorf("division by zero")
+			}
+			return intVal{z.Quo(a, b)}, nil
+		case token.MOD:
+			if b.Sign() == 0 {
+				return MakeUnknown(), fmt.Errorf("division by zero")
+			}
+			return intVal{z.Rem(a, b)}, nil
+		case token.B_AND:
+			return intVal{z.And(a, b)}, nil
+		case token.B_OR:
+			return intVal{z.Or(a, b)}, nil
+		case token.B_XOR:
+			return intVal{z.Xor(a, b)}, nil
+		}
+	case floatVal:
+		a, b := x.val, y.(floatVal).val
+		z := new(big.Rat)
+		switch op {
+		case token.ADD:
+			return floatVal{z.Add(a, b)}, nil
+		case token.SUB:
+			return floatVal{z.Sub(a, b)}, nil
+		case token.MUL:
+			return floatVal{z.Mul(a, b)}, nil
+		case token.DIV:
+			if b.Sign() == 0 {
+				return MakeUnknown(), fmt.Errorf("division by zero")
+			}
+			return floatVal{z.Quo(a, b)}, nil
+		}
+	}
+	return MakeUnknown(), fmt.Errorf("invalid operation: operator %v not defined on %v", op, x)
+}
+
+// Shift returns the result of x << s or x >> s. The shift count s must be a
+// non-negative Int.
+func Shift(x Value, op token.Type, s Value) (Value, error) {
+	if x.Kind() == Unknown || s.Kind() == Unknown {
+		return MakeUnknown(), nil
+	}
+	s = ToInt(s)
+	n, ok := Int64Val(s)
+	if s.Kind() != Int || (ok && n < 0) {
+		return MakeUnknown(), fmt.Errorf("invalid shift count %v", s)
+	}
+	if !ok || n > maxShift {
+		return MakeUnknown(), fmt.Errorf("shift count %v too large", s)
+	}
+	i, ok := ToInt(x).(intVal)
+	if !ok {
+		return MakeUnknown(), fmt.Errorf("invalid operation: shifted operand %v must be an integer", x)
+	}
+	if op == token.LSHIFT {
+		return intVal{new(big.Int).Lsh(i.val, uint(n))}, nil
+	}
+	return intVal{new(big.Int).Rsh(i.val, uint(n))}, nil
+}
+
+func isComparison(op token.Type) bool {
+	switch op {
+	case token.EQUAL, token.NOT_EQUAL, token.LEFT_CARET, token.LT_EQUAL, token.RIGHT_CARET, token.GT_EQUAL:
+		return true
+	}
+	return false
+}
+
+func compare(x Value, op token.Type, y Value) (Value, error) {
+	var c int
+	switch x := x.(type) {
+	case boolVal:
+		if op != token.EQUAL && op != token.NOT_EQUAL {
+			return MakeUnknown(), fmt.Errorf("invalid operation: operator %v not defined on %v", op, x)
+		}
+		if x != y.(boolVal) {
+			c = 1
+		}
+	case stringVal:
+		switch y := y.(stringVal); {
+		case x < y:
+			c = -1
+		case x > y:
+			c = 1
+		}
+	case intVal:
+		c = x.val.Cmp(y.(intVal).val)
+	case floatVal:
+		c = x.val.Cmp(y.(floatVal).val)
+	}
+
+	switch op {
+	case token.EQUAL:
+		return MakeBool(c == 0), nil
+	case token.NOT_EQUAL:
+		return MakeBool(c != 0), nil
+	case token.LEFT_CARET:
+		return MakeBool(c < 0), nil
+	case token.LT_EQUAL:
+		return MakeBool(c <= 0), nil
+	case token.RIGHT_CARET:
+		return MakeBool(c > 0), nil
+	}
+	return MakeBool(c >= 0), nil
+}
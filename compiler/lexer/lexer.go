@@ -11,7 +11,7 @@ import (
 
 const (
 	eof           = -1
-	operatorChars = "()[]<>{}!=+-*/%,._:&|^"
+	operatorChars = "()[]<>{}!=+-*/%,._:&|^?"
 )
 
 type stateFn func(*Lexer) stateFn
@@ -71,17 +71,19 @@ func (l *Lexer) acceptRun(valid string) {
 }
 
 func (l *Lexer) errorf(format string, args ...interface{}) stateFn {
-	l.tokens <- token.Token{token.ERROR, token.Position{l.lineCount(), l.charCount()}, fmt.Sprintf(format, args...)}
+	l.tokens <- token.Token{Type: token.ERROR, Pos: token.Position{Line: l.lineCount(), Char: l.charCount()}, Val: fmt.Sprintf(format, args...)}
 	return nil
 }
 
 func (l *Lexer) emit(t token.Type) {
-	l.tokens <- token.Token{t, token.Position{l.lineCount(), l.charCount()}, l.current()}
+	l.tokens <- token.Token{Type: t, Pos: token.Position{Line: l.lineCount(), Char: l.charCount()}, Val: l.current()}
 	l.start = l.pos
 	l.widths = data.NewStack(10)
 }
 
-func (l *Lexer) emitIndent(indent int) {
+// emitIndent emits the tokens for a change in indentation, returning false
+// if an error was emitted instead.
+func (l *Lexer) emitIndent(indent int) bool {
 	i := l.indentLevels.Peek()
 	if indent > i {
 		l.emit(token.INDENT)
@@ -95,8 +97,10 @@ func (l *Lexer) emitIndent(indent int) {
 		}
 		if l.indentLevels.Len() == 0 || i != indent {
 			l.errorf("Mismatched indentation level encountered.")
+			return false
 		}
 	}
+	return true
 }
 
 func (l *Lexer) lineCount() int {
@@ -135,6 +139,20 @@ func (l *Lexer) NextToken() token.Token {
 	return <-l.tokens
 }
 
+// LexAll lexes input and returns all of its tokens, up to and including the
+// first EOF or ERROR.
+func LexAll(input string) []token.Token {
+	l := Lex(input)
+	var tokens []token.Token
+	for {
+		t := l.NextToken()
+		tokens = append(tokens, t)
+		if t.Type == token.ERROR || t.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
 // lexIndent lexes the initial indentation of a line
 func lexIndent(l *Lexer) stateFn {
 	l.inStmt = false
@@ -160,7 +178,9 @@ func lexIndent(l *Lexer) stateFn {
 		default:
 			l.backup()
 			l.discard()
-			l.emitIndent(indent)
+			if !l.emitIndent(indent) {
+				return nil
+			}
 			return lexStatement
 		}
 	}
@@ -176,6 +196,7 @@ func lexStatement(l *Lexer) stateFn {
 			}
 			l.emitIndent(0)
 			l.emit(token.EOF)
+			return nil
 		case r == ' ' || r == '\t' || r == '\r':
 			l.next()
 			l.discard()
@@ -203,7 +224,7 @@ func lexStatement(l *Lexer) stateFn {
 			l.inStmt = true
 			return lexOperator
 		default:
-			l.errorf("Invalid rune %c encountered.", r)
+			return l.errorf("Invalid rune %c encountered.", r)
 		}
 	}
 }
@@ -0,0 +1,48 @@
+package lexer
+
+import (
+	"github.com/defiant00/char/compiler/token"
+	"testing"
+	"time"
+)
+
+// FuzzLex checks that lexing any input finishes with a single EOF or ERROR
+// token, and that the lexer goroutine exits once it has sent it.
+func FuzzLex(f *testing.F) {
+	f.Add("main\n\tmain()\n\t\tprint(\"hi\")\n")
+	f.Add("a = 'b' + 1.5 << 2 ; comment\n")
+	f.Add("if x\n\tis 1, 2\n\t\tbreak\n")
+	f.Fuzz(func(t *testing.T, input string) {
+		done := make(chan []token.Token)
+		l := Lex(input)
+		go func() {
+			var tokens []token.Token
+			for {
+				tok := l.NextToken()
+				tokens = append(tokens, tok)
+				if tok.Type == token.ERROR || tok.Type == token.EOF {
+					break
+				}
+			}
+			// Nothing may follow the final token.
+			for range l.tokens {
+				tokens = append(tokens, token.Token{Type: token.ERROR, Val: "token after the end"})
+			}
+			done <- tokens
+		}()
+
+		var tokens []token.Token
+		select {
+		case tokens = <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("lexing %q did not finish", input)
+		}
+
+		for i, tok := range tokens {
+			end := tok.Type == token.EOF || tok.Type == token.ERROR
+			if end != (i == len(tokens)-1) {
+				t.Fatalf("lexing %q produced %v at token %v of %v", input, tok, i+1, len(tokens))
+			}
+		}
+	})
+}
@@ -9,10 +9,9 @@ import (
 )
 
 type parser struct {
-	fileName  string        // file name being parsed
-	pos       int           // current position in the token slice
-	tokens    []token.Token // all relevant program tokens
-	fmtTokens []token.Token // all tokens, used to format
+	fileName string        // file name being parsed
+	pos      int           // current position in the token slice
+	tokens   []token.Token // all relevant program tokens
 }
 
 func Parse(file string, build, format, printTokens bool) (ast.General, bool) {
@@ -26,70 +25,154 @@ func Parse(file string, build, format, printTokens bool) (ast.General, bool) {
 	input := string(dat)
 	fmt.Println("Data loaded...")
 
-	l := lexer.Lex(input)
-	p := parser{fileName: file}
-	var t token.Token
+	tokens := lexer.LexAll(input)
 
 	if printTokens {
 		fmt.Println("\nTokens")
+		for _, t := range tokens {
+			fmt.Print(" ", t)
+		}
 	}
 
-	// Read all tokens into a slice.
-	for {
-		t = l.NextToken()
-		if format {
-			p.fmtTokens = append(p.fmtTokens, t)
+	if !build {
+		return &ast.File{Name: file}, false
+	}
+	return ParseTokens(file, tokens)
+}
+
+// ParseTokens parses the tokens of a single file, as returned by
+// lexer.LexAll. Comments are skipped.
+func ParseTokens(file string, tokens []token.Token) (ast.General, bool) {
+	p := parser{fileName: file}
+	for _, t := range tokens {
+		if t.Type == token.ERROR {
+			return &ast.Error{Pos: t.Pos, Val: fmt.Sprintf("\n\n%v\n", t)}, true
 		}
-		if build && t.Type != token.COMMENT {
+		if t.Type != token.COMMENT {
 			p.tokens = append(p.tokens, t)
 		}
-		if printTokens {
-			fmt.Print(" ", t)
-		}
-		if t.Type == token.ERROR || t.Type == token.EOF {
-			break
-		}
-	}
-	if t.Type == token.ERROR {
-		return p.errorStmt(false, "\n\n%v\n", t)
 	}
 	return p.parseFile(), false
 }
 
-func (p *parser) errorStmt(toNextLine bool, format string, args ...interface{}) (ast.Statement, bool) {
-	p.toNextLine(toNextLine)
-	return &ast.Error{Val: fmt.Sprintf(format, args...)}, true
+func (p *parser) errorStmt(format string, args ...interface{}) (ast.Statement, bool) {
+	return &ast.Error{Pos: p.peek().Pos, Val: fmt.Sprintf(format, args...)}, true
 }
 
-func (p *parser) errorExpr(toNextLine bool, format string, args ...interface{}) (ast.Expression, bool) {
-	p.toNextLine(toNextLine)
-	return &ast.Error{Val: fmt.Sprintf(format, args...)}, true
+func (p *parser) errorExpr(format string, args ...interface{}) (ast.Expression, bool) {
+	return &ast.Error{Pos: p.peek().Pos, Val: fmt.Sprintf(format, args...)}, true
 }
 
-func (p *parser) toNextLine(toNextLine bool) {
-	if !toNextLine {
-		return
+// skipStmt recovers from an error in the statement that started at token
+// start by moving past all of it: everything up to the first EOL outside of
+// brackets and indented blocks, along with the indented block after that EOL
+// if there is one. Skipping from the start of the statement rather than from
+// the error is what lets brackets and multi-line expressions be tracked.
+func (p *parser) skipStmt(start int) {
+	p.pos = start
+	// Open brackets are counted per indentation level, so that an unclosed
+	// bracket inside an indented block is forgotten once the block ends.
+	var outer []int
+	brackets := 0
+	prev := token.EOL
+loop:
+	for {
+		t := p.peek().Type
+		switch t {
+		case token.EOF:
+			break loop
+		case token.LEFT_PAREN, token.LEFT_BRACKET, token.LEFT_CURLY:
+			brackets++
+		case token.RIGHT_PAREN, token.RIGHT_BRACKET, token.RIGHT_CURLY:
+			if brackets > 0 {
+				brackets--
+			}
+		case token.INDENT:
+			outer = append(outer, brackets)
+			brackets = 0
+		case token.DEDENT:
+			if len(outer) == 0 {
+				break loop // the end of the enclosing block
+			}
+			brackets, outer = outer[len(outer)-1], outer[:len(outer)-1]
+		case token.EOL:
+			// A line break only ends the statement if it isn't followed by an
+			// indented block and doesn't close a multi-line list, i.e.
+			// "EOL DEDENT EOL }".
+			closesList := brackets > 0 && prev == token.DEDENT &&
+				(p.isNext(1, token.RIGHT_PAREN) || p.isNext(1, token.RIGHT_BRACKET) || p.isNext(1, token.RIGHT_CURLY))
+			if len(outer) == 0 && !closesList && !p.isNext(1, token.INDENT) {
+				p.next()
+				break loop
+			}
+		}
+		prev = t
+		p.next()
 	}
+	if p.pos == start && p.peek().Type != token.EOF {
+		p.next() // always make progress
+	}
+}
 
-	t := p.peek().Type
-	for ; t != token.EOL && t != token.EOF; t = p.peek().Type {
+// skipLine moves up to, but not past, the EOL that ends the current line.
+func (p *parser) skipLine() {
+	for t := p.peek().Type; t != token.EOL && t != token.EOF; t = p.peek().Type {
 		p.next()
 	}
-	if t == token.EOL {
-		p.next()
-		for {
-			if succ, _ := p.accept(token.DEDENT, token.EOL); !succ {
-				return
-			}
+}
+
+// isNext returns whether the token offset positions ahead is of type typ.
+func (p *parser) isNext(offset int, typ token.Type) bool {
+	i := p.pos + offset
+	return i < len(p.tokens) && p.tokens[i].Type == typ
+}
+
+// startBlock consumes the EOL and INDENT that separate a block statement's
+// header from its body, returning whether they were found along with the
+// offending token if not. If the header had an error the rest of its line is
+// skipped first, so that the body can still be parsed.
+func (p *parser) startBlock(headerErr bool) (bool, token.Token) {
+	if headerErr {
+		p.skipLine()
+	}
+	succ, toks := p.accept(token.EOL, token.INDENT)
+	return succ, toks[len(toks)-1]
+}
+
+// parseBlock parses statements with parse until the end of the current
+// indented block, then consumes its DEDENT and EOL. Every statement is passed
+// to add, unless it is nil. A statement that fails to parse is added as the
+// error it returned and then skipped, so the rest of the block is unaffected.
+func (p *parser) parseBlock(context string, parse func() (ast.Statement, bool), add func(ast.Statement)) {
+	for p.peek().Type != token.DEDENT && p.peek().Type != token.EOF {
+		start := p.pos
+		st, err := parse()
+		if st != nil {
+			add(st)
+		}
+		if err {
+			p.skipStmt(start)
 		}
 	}
+	if succ, toks := p.accept(token.DEDENT, token.EOL); !succ {
+		st, _ := p.errorStmt("Invalid token in %v: %v", context, toks[len(toks)-1])
+		add(st)
+	}
 }
 
 func (p *parser) tokensAvailable() int {
 	return len(p.tokens) - p.pos
 }
 
+// peek returns the current token. Past the end of the input it keeps
+// returning the final token, which is always an EOF.
 func (p *parser) peek() token.Token {
+	if p.pos >= len(p.tokens) {
+		if len(p.tokens) == 0 {
+			return token.Token{Type: token.EOF}
+		}
+		return p.tokens[len(p.tokens)-1]
+	}
 	return p.tokens[p.pos]
 }
 
@@ -100,8 +183,11 @@ func (p *parser) next() token.Token {
 }
 
 // peekCombo returns the next token, or combines >>
-// into rshift.
+// into rshift. A <- is a < where an operator is expected.
 func (p *parser) peekCombo() token.Token {
+	if t := p.peek(); t.Type == token.ARROW {
+		return token.Token{Type: token.LEFT_CARET, Val: "<", Pos: t.Pos}
+	}
 	t := p.next()
 	if p.tokensAvailable() > 0 {
 		t2 := p.peek()
@@ -115,8 +201,15 @@ func (p *parser) peekCombo() token.Token {
 }
 
 // nextCombo consumes and returns the next token, or
-// combines >> into rshift.
+// combines >> into rshift. A <- is split into a < and
+// a - that is left for the operand.
 func (p *parser) nextCombo() token.Token {
+	if t := p.peek(); t.Type == token.ARROW {
+		lt := token.Token{Type: token.LEFT_CARET, Val: "<", Pos: t.Pos}
+		sub := token.Token{Type: token.SUB, Val: "-", Pos: token.Position{Line: t.Pos.Line, Char: t.Pos.Char + 1}}
+		p.tokens = append(p.tokens[:p.pos], append([]token.Token{lt, sub}, p.tokens[p.pos+1:]...)...)
+		return p.next()
+	}
 	t := p.next()
 	t2 := p.next()
 	if t.Type == token.RIGHT_CARET && t2.Type == token.RIGHT_CARET {
@@ -149,46 +242,110 @@ func (p *parser) accept(types ...token.Type) (bool, []token.Token) {
 func (p *parser) parseFile() ast.General {
 	f := &ast.File{Name: p.fileName}
 	for p.pos < len(p.tokens) {
+		start := p.pos
+		var st ast.Statement
+		var err bool
 		switch p.peek().Type {
 		case token.EOF:
 			p.next()
-		case token.IDENTIFIER:
-			st, _ := p.parseTopLevelIdent()
-			f.AddStmt(st)
-		case token.FUNCTION:
-			st, _ := p.parseAlias()
-			f.AddStmt(st)
+			continue
 		case token.USE:
-			st, _ := p.parseUse()
-			f.AddStmt(st)
-		case token.INTERFACE:
-			st, _ := p.parseInterface()
-			f.AddStmt(st)
-		case token.MIXIN:
-			st, _ := p.parseMixin()
-			f.AddStmt(st)
+			for _, st := range p.parseUse() {
+				f.AddStmt(st)
+			}
+			continue
+		case token.PRIV:
+			st, err = p.parsePrivate(p.parseDecl)
 		default:
-			st, _ := p.errorStmt(true, "Invalid token %v", p.peek())
-			f.AddStmt(st)
+			st, err = p.parseDecl()
+		}
+		f.AddStmt(st)
+		if err {
+			p.skipStmt(start)
 		}
 	}
 	return f
 }
 
+// parseDecl parses a top-level declaration.
+func (p *parser) parseDecl() (ast.Statement, bool) {
+	switch p.peek().Type {
+	case token.IDENTIFIER:
+		return p.parseTopLevelIdent()
+	case token.FUNCTION, token.ARRAY:
+		return p.parseAlias()
+	case token.INTERFACE:
+		return p.parseInterface()
+	case token.MIXIN:
+		return p.parseMixin()
+	case token.ENUM:
+		return p.parseEnum()
+	}
+	return p.errorStmt("Invalid token %v", p.peek())
+}
+
+// parsePrivate parses a declaration or class member marked priv with parse,
+// which makes it only visible in its own package.
+func (p *parser) parsePrivate(parse func() (ast.Statement, bool)) (ast.Statement, bool) {
+	p.next() // eat priv
+	if t := p.peek().Type; t == token.PRIV || t == token.IOTA {
+		return p.errorStmt("Invalid token after priv: %v", p.peek())
+	}
+	st, err := parse()
+	if err {
+		return st, true
+	}
+	switch st := st.(type) {
+	case *ast.Alias:
+		st.Private = true
+	case *ast.Class:
+		st.Private = true
+	case *ast.Interface:
+		st.Private = true
+	case *ast.PropertySet:
+		st.Private = true
+	case *ast.FunctionDef:
+		st.Private = true
+	}
+	return st, false
+}
+
 func (p *parser) parseInterface() (ast.Statement, bool) {
 	succ, toks := p.accept(token.INTERFACE, token.IDENTIFIER)
 	if !succ {
-		return p.errorStmt(true, "Invalid token in interface: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in interface: %v", toks[len(toks)-1])
+	}
+
+	intf := &ast.Interface{Pos: toks[0].Pos, Name: toks[1].Val}
+
+	hdr, hdrErr := p.parseInterfaceWiths(intf)
+	if succ, tok := p.startBlock(hdrErr); !succ {
+		if hdrErr {
+			return hdr, true
+		}
+		return p.errorStmt("Invalid token in interface %v: %v", intf.Name, tok)
 	}
+	if hdrErr {
+		intf.AddFuncSig(hdr)
+	}
+
+	p.parseBlock("interface "+intf.Name, func() (ast.Statement, bool) {
+		return p.parseIntfFuncSig(intf.Name)
+	}, intf.AddFuncSig)
 
-	intf := &ast.Interface{Name: toks[1].Val}
+	return intf, false
+}
 
+func (p *parser) parseInterfaceWiths(intf *ast.Interface) (ast.Statement, bool) {
 	if succ, _ := p.accept(token.WITH); succ {
 		for {
 			if p.peek().Type != token.IDENTIFIER {
-				return p.errorStmt(true, "Invalid token in interface %v: %v", intf.Name, p.peek())
+				return p.errorStmt("Invalid token in interface %v: %v", intf.Name, p.peek())
+			}
+			st, err := p.parseTypeIdent()
+			if err {
+				return st, true
 			}
-			st, _ := p.parseTypeIdent()
 			intf.AddWith(st)
 
 			if succ, _ = p.accept(token.COMMA); !succ {
@@ -196,48 +353,45 @@ func (p *parser) parseInterface() (ast.Statement, bool) {
 			}
 		}
 	}
+	return nil, false
+}
 
-	if succ, toks = p.accept(token.EOL, token.INDENT); !succ {
-		return p.errorStmt(true, "Invalid token in interface %v: %v", intf.Name, toks[len(toks)-1])
+func (p *parser) parseIntfFuncSig(intfName string) (ast.Statement, bool) {
+	// function_name(types)
+	succ, toks := p.accept(token.IDENTIFIER, token.LEFT_PAREN)
+	if !succ {
+		return p.errorStmt("Invalid token in interface %v: %v", intfName, toks[len(toks)-1])
 	}
-
-	for p.peek().Type != token.DEDENT {
-		// function_name(types)
-		succ, toks = p.accept(token.IDENTIFIER, token.LEFT_PAREN)
-		if !succ {
-			return p.errorStmt(true, "Invalid token in interface %v: %v", intf.Name, toks[len(toks)-1])
-		}
-		fs := &ast.IntfFuncSig{Name: toks[0].Val}
-		for p.peek().Type != token.RIGHT_PAREN {
-			st, _ := p.parseType()
-			fs.AddParam(st)
-			switch p.peek().Type {
-			case token.COMMA:
-				p.next() // eat ,
-			case token.RIGHT_PAREN:
-			default:
-				return p.errorStmt(true, "Invalid token in interface %v function signature %v: %v", intf.Name, fs.Name, p.peek())
-			}
-		}
-		p.next() // eat )
-
-		// return value(s)
-		rvs, _ := p.parseReturnValues()
-		for _, rv := range rvs {
-			fs.AddReturn(rv)
+	fs := &ast.IntfFuncSig{Pos: toks[0].Pos, Name: toks[0].Val}
+	for p.peek().Type != token.RIGHT_PAREN {
+		st, err := p.parseType()
+		if err {
+			return st, true
 		}
-
-		if succ, _ = p.accept(token.EOL); !succ {
-			return p.errorStmt(true, "Invalid token in interface %v function signature %v: %v", intf.Name, fs.Name, p.peek())
+		fs.AddParam(st)
+		switch p.peek().Type {
+		case token.COMMA:
+			p.next() // eat ,
+		case token.RIGHT_PAREN:
+		default:
+			return p.errorStmt("Invalid token in interface %v function signature %v: %v", intfName, fs.Name, p.peek())
 		}
-		intf.AddFuncSig(fs)
 	}
+	p.next() // eat )
 
-	if succ, toks = p.accept(token.DEDENT, token.EOL); !succ {
-		return p.errorStmt(true, "Invalid token in interface %v: %v", intf.Name, toks[len(toks)-1])
+	// return value(s)
+	rvs, err := p.parseReturnValues()
+	if err {
+		return rvs[len(rvs)-1], true
+	}
+	for _, rv := range rvs {
+		fs.AddReturn(rv)
 	}
 
-	return intf, false
+	if succ, _ = p.accept(token.EOL); !succ {
+		return p.errorStmt("Invalid token in interface %v function signature %v: %v", intfName, fs.Name, p.peek())
+	}
+	return fs, false
 }
 
 func (p *parser) parseTopLevelIdent() (ast.Statement, bool) {
@@ -252,7 +406,7 @@ func (p *parser) parseTopLevelIdent() (ast.Statement, bool) {
 // if it encounters an AS it returns true, otherwise false.
 func (p *parser) isAlias() bool {
 	count := 0
-	for p.peek().Type != token.EOL {
+	for p.peek().Type != token.EOL && p.peek().Type != token.EOF {
 		if p.peek().Type == token.AS {
 			p.backup(count)
 			return true
@@ -269,63 +423,130 @@ func (p *parser) parseMixin() (ast.Statement, bool) {
 	return p.parseClass(true)
 }
 
+// parseEnum parses an enum, which is lowered to a class with an iota constant
+// for each of its names, along with native string and parse functions that
+// convert between them and their names.
+func (p *parser) parseEnum() (ast.Statement, bool) {
+	succ, toks := p.accept(token.ENUM, token.IDENTIFIER)
+	if !succ {
+		return p.errorStmt("Invalid token in enum: %v", toks[len(toks)-1])
+	}
+	c := &ast.Class{Pos: toks[1].Pos, Enum: true, Name: toks[1].Val}
+	if succ, tok := p.startBlock(false); !succ {
+		return p.errorStmt("Invalid token in enum %v: %v", c.Name, tok)
+	}
+
+	first := true
+	p.parseBlock("enum "+c.Name, func() (ast.Statement, bool) {
+		succ, toks := p.accept(token.IDENTIFIER, token.EOL)
+		if !succ {
+			return p.errorStmt("Invalid token in enum %v: %v", c.Name, toks[len(toks)-1])
+		}
+		ps := &ast.PropertySet{Pos: toks[0].Pos}
+		ps.AddProp(true, toks[0].Val, nil)
+		if first {
+			vals := &ast.ExprList{Pos: toks[0].Pos}
+			vals.AddExpr(&ast.Iota{Pos: toks[0].Pos})
+			ps.Vals = vals
+			first = false
+		}
+		return ps, false
+	}, c.AddStmt)
+
+	str := &ast.FunctionDef{Pos: c.Pos, Static: true, Native: true, Name: "string"}
+	str.AddParam("v", ast.NewTypeIdent(c.Pos, c.Name))
+	str.AddReturn(ast.NewTypeIdent(c.Pos, "string"))
+	c.AddStmt(str)
+
+	parse := &ast.FunctionDef{Pos: c.Pos, Static: true, Native: true, Name: "parse"}
+	parse.AddParam("s", ast.NewTypeIdent(c.Pos, "string"))
+	parse.AddReturn(ast.NewTypeIdent(c.Pos, c.Name))
+	parse.AddReturn(ast.NewTypeIdent(c.Pos, "Error"))
+	c.AddStmt(parse)
+
+	return c, false
+}
+
 func (p *parser) parseAlias() (ast.Statement, bool) {
-	st, _ := p.parseType()
-	a := &ast.Alias{Val: st}
+	pos := p.peek().Pos
+	st, err := p.parseType()
+	if err {
+		return st, true
+	}
+	a := &ast.Alias{Pos: pos, Val: st}
 
 	if succ, toks := p.accept(token.AS); !succ {
-		return p.errorStmt(true, "Invalid token in alias: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in alias: %v", toks[len(toks)-1])
 	}
 
 	succ, toks := p.accept(token.IDENTIFIER, token.EOL)
 	if !succ {
-		return p.errorStmt(true, "Invalid token in alias: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in alias: %v", toks[len(toks)-1])
 	}
 	a.Alias = toks[0].Val
 	return a, false
 }
 
 func (p *parser) parseType() (ast.Statement, bool) {
+	var st ast.Statement
+	var err bool
 	switch p.peek().Type {
 	case token.IDENTIFIER:
-		return p.parseTypeIdent()
+		st, err = p.parseTypeIdent()
 	case token.ARRAY:
-		return p.parseArrayType()
+		st, err = p.parseArrayType()
 	case token.FUNCTION:
-		return p.parseFuncSigType()
+		st, err = p.parseFuncSigType()
 	default:
-		return p.errorStmt(true, "Invalid token in type identifier: %v", p.peek())
+		return p.errorStmt("Invalid token in type identifier: %v", p.peek())
+	}
+	if err {
+		return st, true
+	}
+	if p.peek().Type == token.QUESTION {
+		p.next() // eat ?
+		return &ast.Optional{Pos: ast.PosOf(st), Type: st}, false
 	}
+	return st, false
 }
 
 func (p *parser) parseArrayType() (ast.Statement, bool) {
-	p.next() // eat []
-	st, _ := p.parseType()
-	return &ast.Array{Type: st}, false
+	pos := p.next().Pos // eat []
+	st, err := p.parseType()
+	if err {
+		return st, true
+	}
+	return &ast.Array{Pos: pos, Type: st}, false
 }
 
 func (p *parser) parseFuncSigType() (ast.Statement, bool) {
-	f := &ast.FunctionSig{}
+	f := &ast.FunctionSig{Pos: p.peek().Pos}
 
 	// fn(types)
 	if succ, toks := p.accept(token.FUNCTION, token.LEFT_PAREN); !succ {
-		return p.errorStmt(true, "Invalid token in anonymous function: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in anonymous function: %v", toks[len(toks)-1])
 	}
 	for p.peek().Type != token.RIGHT_PAREN {
-		st, _ := p.parseType()
+		st, err := p.parseType()
+		if err {
+			return st, true
+		}
 		f.AddParam(st)
 		switch p.peek().Type {
 		case token.COMMA:
 			p.next() // eat ,
 		case token.RIGHT_PAREN:
 		default:
-			return p.errorStmt(true, "Invalid token in anonymous function: %v", p.peek())
+			return p.errorStmt("Invalid token in anonymous function: %v", p.peek())
 		}
 	}
 	p.next() // eat )
 
 	// return value(s)
-	rvs, _ := p.parseReturnValues()
+	rvs, err := p.parseReturnValues()
+	if err {
+		return rvs[len(rvs)-1], true
+	}
 	for _, rv := range rvs {
 		f.AddReturn(rv)
 	}
@@ -336,32 +557,61 @@ func (p *parser) parseFuncSigType() (ast.Statement, bool) {
 func (p *parser) parseClass(mixin bool) (ast.Statement, bool) {
 	succ, toks := p.accept(token.IDENTIFIER)
 	if !succ {
-		return p.errorStmt(true, "Invalid token in class declaration: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in class declaration: %v", toks[len(toks)-1])
 	}
-	c := &ast.Class{Mixin: mixin, Name: toks[0].Val}
+	c := &ast.Class{Pos: toks[0].Pos, Mixin: mixin, Name: toks[0].Val}
 
+	hdr, hdrErr := p.parseClassHeader(c)
+	if succ, tok := p.startBlock(hdrErr); !succ {
+		if hdrErr {
+			return hdr, true
+		}
+		return p.errorStmt("Invalid token in class %v declaration: %v", c.Name, tok)
+	}
+	if hdrErr {
+		c.AddStmt(hdr)
+	}
+
+	p.parseBlock(fmt.Sprintf("class %v declaration", c.Name), p.parseClassStmt, c.AddStmt)
+
+	return c, false
+}
+
+// parseClassHeader parses a class's optional type parameters and withs.
+func (p *parser) parseClassHeader(c *ast.Class) (ast.Statement, bool) {
 	if succ, _ := p.accept(token.LEFT_CARET); succ {
 		for {
 			succ, toks := p.accept(token.IDENTIFIER)
 			if !succ {
-				return p.errorStmt(true, "Invalid token in class %v type declaration: %v", c.Name, toks[len(toks)-1])
+				return p.errorStmt("Invalid token in class %v type declaration: %v", c.Name, toks[len(toks)-1])
 			}
-			c.AddTypeParam(toks[0].Val)
+			var bound ast.Statement
+			if p.peek().Type.IsType() {
+				var err bool
+				bound, err = p.parseType()
+				if err {
+					return bound, true
+				}
+			}
+			c.AddTypeParam(toks[0].Val, bound)
 			if succ, _ := p.accept(token.COMMA); !succ {
 				break
 			}
 		}
-		if succ, _ = p.accept(token.RIGHT_CARET); !succ {
-			return p.errorStmt(true, "Invalid token in class %v type declaration: %v", c.Name, toks[len(toks)-1])
+		if succ, toks := p.accept(token.RIGHT_CARET); !succ {
+			return p.errorStmt("Invalid token in class %v type declaration: %v", c.Name, toks[len(toks)-1])
 		}
 	}
 
 	if succ, _ := p.accept(token.WITH); succ {
 		for {
 			if p.peek().Type != token.IDENTIFIER {
-				return p.errorStmt(true, "Invalid token in class %v with declaration: %v", c.Name, p.peek())
+				return p.errorStmt("Invalid token in class %v with declaration: %v", c.Name, p.peek())
+			}
+			st, err := p.parseTypeIdent()
+			if err {
+				return st, true
 			}
-			st, _ := p.parseTypeIdent()
 			c.AddWith(st)
 
 			if succ, _ = p.accept(token.COMMA); !succ {
@@ -369,22 +619,7 @@ func (p *parser) parseClass(mixin bool) (ast.Statement, bool) {
 			}
 		}
 	}
-
-	if succ, toks := p.accept(token.EOL, token.INDENT); !succ {
-		return p.errorStmt(true, "Invalid token in class %v declaration: %v", c.Name, toks[len(toks)-1])
-	}
-
-	for p.peek().Type != token.DEDENT && p.peek().Type != token.EOF {
-		st, _ := p.parseClassStmt()
-		c.AddStmt(st)
-	}
-
-	if succ, toks := p.accept(token.DEDENT, token.EOL); !succ {
-		st, _ := p.errorStmt(true, "Invalid token in class %v declaration: %v", c.Name, toks[len(toks)-1])
-		c.AddStmt(st)
-	}
-
-	return c, false
+	return nil, false
 }
 
 func (p *parser) parseClassStmt() (ast.Statement, bool) {
@@ -393,8 +628,12 @@ func (p *parser) parseClassStmt() (ast.Statement, bool) {
 		return p.parseClassStmtIdent()
 	case token.IOTA:
 		return p.parseIotaStmt()
+	case token.NATIVE:
+		return p.parseNativeFunc()
+	case token.PRIV:
+		return p.parsePrivate(p.parseClassStmt)
 	}
-	return p.errorStmt(true, "Invalid token in class statement: %v", p.peek())
+	return p.errorStmt("Invalid token in class statement: %v", p.peek())
 }
 
 func (p *parser) parseFuncStmt() (ast.Statement, bool) {
@@ -409,6 +648,10 @@ func (p *parser) parseFuncStmt() (ast.Statement, bool) {
 		return p.parseIfStmt()
 	case token.BREAK:
 		return p.parseBreakStmt()
+	case token.SPAWN:
+		return p.parseSpawnStmt()
+	case token.SELECT:
+		return p.parseSelectStmt()
 	case token.FOR, token.LOOP:
 		return p.parseForOrLoop("")
 	default:
@@ -420,13 +663,13 @@ func (p *parser) parseFuncStmt() (ast.Statement, bool) {
 }
 
 func (p *parser) parseBreakStmt() (ast.Statement, bool) {
-	p.next() // eat break
-	b := &ast.Break{}
+	pos := p.next().Pos // eat break
+	b := &ast.Break{Pos: pos}
 	if succ, toks := p.accept(token.IDENTIFIER); succ {
 		b.Label = toks[0].Val
 	}
 	if succ, toks := p.accept(token.EOL); !succ {
-		return p.errorStmt(true, "Invalid token in break: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in break: %v", toks[len(toks)-1])
 	}
 	return b, false
 }
@@ -438,18 +681,36 @@ func (p *parser) parseForOrLoop(label string) (ast.Statement, bool) {
 	case token.LOOP:
 		return p.parseLoopStmt(label)
 	default:
-		return p.errorStmt(true, "Invalid token after label: %v", p.peek())
+		return p.errorStmt("Invalid token after label: %v", p.peek())
 	}
 }
 
 func (p *parser) parseForStmt(label string) (ast.Statement, bool) {
-	p.next() // eat for
+	pos := p.next().Pos // eat for
+
+	f := &ast.For{Pos: pos, Label: label}
+	hdr, hdrErr := p.parseForHeader(f)
+	if succ, tok := p.startBlock(hdrErr); !succ {
+		if hdrErr {
+			return hdr, true
+		}
+		return p.errorStmt("Invalid token in for: %v", tok)
+	}
+	if hdrErr {
+		f.AddStmt(hdr)
+	}
 
-	f := &ast.For{Label: label}
+	p.parseBlock("for", p.parseFuncStmt, f.AddStmt)
+
+	return f, false
+}
+
+// parseForHeader parses the variables and expression of a for loop.
+func (p *parser) parseForHeader(f *ast.For) (ast.Statement, bool) {
 	for {
 		succ, toks := p.accept(token.IDENTIFIER)
 		if !succ {
-			return p.errorStmt(true, "Invalid token in for: %v", toks[len(toks)-1])
+			return p.errorStmt("Invalid token in for: %v", toks[len(toks)-1])
 		}
 		f.AddVar(toks[0].Val)
 		if succ, toks = p.accept(token.COMMA); !succ {
@@ -458,7 +719,7 @@ func (p *parser) parseForStmt(label string) (ast.Statement, bool) {
 	}
 
 	if succ, toks := p.accept(token.IN); !succ {
-		return p.errorStmt(true, "Invalid token in for: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in for: %v", toks[len(toks)-1])
 	}
 
 	in, err := p.parseExpr()
@@ -466,39 +727,17 @@ func (p *parser) parseForStmt(label string) (ast.Statement, bool) {
 		return in.(ast.Statement), true
 	}
 	f.In = in
-
-	if succ, toks := p.accept(token.EOL, token.INDENT); !succ {
-		return p.errorStmt(true, "Invalid token in for: %v", toks[len(toks)-1])
-	}
-
-	for p.peek().Type != token.DEDENT && p.peek().Type != token.EOF {
-		st, _ := p.parseFuncStmt()
-		f.AddStmt(st)
-	}
-
-	if succ, toks := p.accept(token.DEDENT, token.EOL); !succ {
-		st, _ := p.errorStmt(true, "Invalid token in for: %v", toks[len(toks)-1])
-		f.AddStmt(st)
-	}
-
-	return f, false
+	return nil, false
 }
 
 func (p *parser) parseLoopStmt(label string) (ast.Statement, bool) {
-	if succ, toks := p.accept(token.LOOP, token.EOL, token.INDENT); !succ {
-		return p.errorStmt(true, "Invalid token in loop: %v", toks[len(toks)-1])
+	succ, toks := p.accept(token.LOOP, token.EOL, token.INDENT)
+	if !succ {
+		return p.errorStmt("Invalid token in loop: %v", toks[len(toks)-1])
 	}
 
-	l := &ast.Loop{Label: label}
-	for p.peek().Type != token.DEDENT && p.peek().Type != token.EOF {
-		st, _ := p.parseFuncStmt()
-		l.AddStmt(st)
-	}
-
-	if succ, toks := p.accept(token.DEDENT, token.EOL); !succ {
-		st, _ := p.errorStmt(true, "Invalid token in loop: %v", toks[len(toks)-1])
-		l.AddStmt(st)
-	}
+	l := &ast.Loop{Pos: toks[0].Pos, Label: label}
+	p.parseBlock("loop", p.parseFuncStmt, l.AddStmt)
 
 	return l, false
 }
@@ -513,111 +752,314 @@ func (p *parser) parseIfInnerStmt() (ast.Statement, bool) {
 }
 
 func (p *parser) parseIsStmt() (ast.Statement, bool) {
-	p.next() // eat is
-	cond, _ := p.parseExprList()
-	if succ, toks := p.accept(token.EOL, token.INDENT); !succ {
-		return p.errorStmt(true, "Invalid token in is statement: %v", toks[len(toks)-1])
+	pos := p.next().Pos // eat is
+	iss := &ast.Is{Pos: pos}
+
+	cond, condErr := p.parsePatternList()
+	if succ, tok := p.startBlock(condErr); !succ {
+		if condErr {
+			return cond.(ast.Statement), true
+		}
+		return p.errorStmt("Invalid token in is statement: %v", tok)
+	}
+	if condErr {
+		iss.AddStmt(cond.(ast.Statement))
+	} else {
+		iss.Condition = cond
 	}
 
-	iss := &ast.Is{Condition: cond}
-	for p.peek().Type != token.DEDENT && p.peek().Type != token.EOF {
-		st, _ := p.parseFuncStmt()
-		iss.AddStmt(st)
+	p.parseBlock("is statement", p.parseFuncStmt, iss.AddStmt)
+
+	return iss, false
+}
+
+// parsePatternList parses the comma separated patterns of an is case.
+func (p *parser) parsePatternList() (ast.Expression, bool) {
+	el := &ast.ExprList{Pos: p.peek().Pos}
+	for {
+		pat, err := p.parsePattern()
+		if err {
+			return pat, true
+		}
+		el.AddExpr(pat)
+		if succ, _ := p.accept(token.COMMA); !succ {
+			return el, false
+		}
 	}
+}
 
-	if succ, toks := p.accept(token.DEDENT, token.EOL); !succ {
-		st, _ := p.errorStmt(true, "Invalid token in is statement: %v", toks[len(toks)-1])
-		iss.AddStmt(st)
+// parsePattern parses a single pattern: a type followed by a name to bind
+// (Circle c), a type followed by field patterns (Point{x: 0, y}), a range
+// (1 to 9), or a plain expression to compare against.
+func (p *parser) parsePattern() (ast.Expression, bool) {
+	pos := p.peek().Pos
+	if p.peek().Type == token.IDENTIFIER {
+		start := p.pos
+		typ, err := p.parseType()
+		if !err {
+			switch p.peek().Type {
+			case token.IDENTIFIER:
+				return &ast.TypePattern{Pos: pos, Type: typ, Name: p.next().Val}, false
+			case token.LEFT_CURLY:
+				return p.parseShapePattern(typ)
+			}
+		}
+		p.pos = start // not a type, so parse it as an expression instead
 	}
 
-	return iss, false
+	low, err := p.parseExpr()
+	if err {
+		return low, true
+	}
+	if succ, _ := p.accept(token.TO); !succ {
+		return low, false
+	}
+	high, err := p.parseExpr()
+	if err {
+		return high, true
+	}
+	return &ast.RangePattern{Pos: pos, Low: low, High: high}, false
+}
+
+func (p *parser) parseShapePattern(typ ast.Statement) (ast.Expression, bool) {
+	sp := &ast.ShapePattern{Pos: ast.PosOf(typ), Type: typ}
+	p.next() // eat {
+	for p.peek().Type != token.RIGHT_CURLY {
+		succ, toks := p.accept(token.IDENTIFIER)
+		if !succ {
+			return p.errorExpr("Invalid token in pattern: %v", toks[len(toks)-1])
+		}
+		f := &ast.KeyVal{Pos: toks[0].Pos, Key: toks[0].Val}
+		if succ, _ := p.accept(token.COLON); succ {
+			pat, err := p.parsePattern()
+			if err {
+				return pat, true
+			}
+			f.Val = pat
+		}
+		sp.AddField(f)
+		switch p.peek().Type {
+		case token.COMMA:
+			p.next() // eat ,
+		case token.RIGHT_CURLY:
+		default:
+			return p.errorExpr("Invalid token in pattern: %v", p.peek())
+		}
+	}
+	p.next() // eat }
+	return sp, false
 }
 
 func (p *parser) parseIfStmt() (ast.Statement, bool) {
-	p.next() // eat if
-	var cond ast.Expression
-	var err bool
+	pos := p.next().Pos // eat if
+	ifs := &ast.If{Pos: pos}
+
+	hdr, hdrErr := p.parseIfHeader(ifs)
+	if succ, tok := p.startBlock(hdrErr); !succ {
+		if hdrErr {
+			return hdr, true
+		}
+		return p.errorStmt("Invalid token in if statement: %v", tok)
+	}
+	if hdrErr {
+		ifs.AddStmt(hdr)
+	}
+
+	p.parseBlock("if statement", p.parseIfInnerStmt, ifs.AddStmt)
+
+	return ifs, false
+}
+
+// parseIfHeader parses the optional condition and with statement of an if.
+func (p *parser) parseIfHeader(ifs *ast.If) (ast.Statement, bool) {
 	if p.peek().Type != token.EOL && p.peek().Type != token.WITH {
-		cond, err = p.parseExpr()
+		cond, err := p.parseExpr()
 		if err {
 			return cond.(ast.Statement), true
 		}
+		ifs.Condition = cond
 	}
-	var with ast.Statement
 	if succ, _ := p.accept(token.WITH); succ {
+		var with ast.Statement
+		var err bool
 		switch p.peek().Type {
 		case token.VAR:
-			with, _ = p.parseVarStmt(true)
+			with, err = p.parseVarStmt(true)
 		default:
-			with, _ = p.parseExprStmt(true)
+			with, err = p.parseExprStmt(true)
+		}
+		if err {
+			return with, true
 		}
+		ifs.With = with
 	}
-	if succ, toks := p.accept(token.EOL, token.INDENT); !succ {
-		return p.errorStmt(true, "Invalid token in if statement: %v", toks[len(toks)-1])
+	return nil, false
+}
+
+func (p *parser) parseExprStmt(inWith bool) (ast.Statement, bool) {
+	pos := p.peek().Pos
+	// A <- right after the first operand and the members of it that it gets
+	// is a send. Anywhere else in the statement it's a < and a -, as in
+	// x<-1.
+	first, err := p.parsePrimaryExpr()
+	if err {
+		return first.(ast.Statement), true
+	}
+	dot := token.Token{Type: token.DOT}
+	if first, err = p.parseBinopRHS(dot.Precedence(), first); err {
+		return first.(ast.Statement), true
+	}
+	var ex ast.Expression
+	var stmt ast.Statement
+	if p.peek().Type == token.ARROW {
+		stmt, err = p.parseSendStmt(pos, first)
+	} else if ex, err = p.parseExprListFrom(pos, first); err {
+		return ex.(ast.Statement), true
+	} else if p.peek().Type.IsAssign() {
+		stmt, err = p.parseAssignStmt(ex)
+	} else if isSplitArrow(ex) {
+		return &ast.Error{Pos: pos, Val: "Invalid send, <- must follow the channel"}, true
+	}
+	if err {
+		return stmt, true
 	}
+	if !inWith {
+		if succ, toks := p.accept(token.EOL); !succ {
+			return p.errorStmt("Invalid token in expression statement: %v", toks[len(toks)-1])
+		}
+	}
+	if stmt != nil {
+		return stmt, false
+	}
+	return &ast.ExprStmt{Pos: pos, Expr: ex}, false
+}
 
-	ifs := &ast.If{Condition: cond, With: with}
-	for p.peek().Type != token.DEDENT && p.peek().Type != token.EOF {
-		st, _ := p.parseIfInnerStmt()
-		ifs.AddStmt(st)
+// isSplitArrow returns whether ex is a single comparison against a negated
+// value, such as a + b <- 1, which is meant as a send but can only be a
+// comparison whose result is thrown away.
+func isSplitArrow(ex ast.Expression) bool {
+	exprs := ex.(*ast.ExprList).Exprs()
+	if len(exprs) != 1 {
+		return false
+	}
+	b, ok := exprs[0].(*ast.Binary)
+	if !ok || b.Op != token.LEFT_CARET {
+		return false
 	}
+	u, ok := b.Right.(*ast.Unary)
+	return ok && u.Op == token.SUB
+}
 
-	if succ, toks := p.accept(token.DEDENT, token.EOL); !succ {
-		st, _ := p.errorStmt(true, "Invalid token in if statement: %v", toks[len(toks)-1])
-		ifs.AddStmt(st)
+// parseSendStmt parses the value sent on the channel ch.
+func (p *parser) parseSendStmt(pos token.Position, ch ast.Expression) (ast.Statement, bool) {
+	p.next() // eat <-
+	val, err := p.parseExpr()
+	if err {
+		return val.(ast.Statement), true
 	}
+	return &ast.Send{Pos: pos, Chan: ch, Val: val}, false
+}
 
-	return ifs, false
+func (p *parser) parseSpawnStmt() (ast.Statement, bool) {
+	pos := p.next().Pos // eat spawn
+	ex, err := p.parseExpr()
+	if err {
+		return ex.(ast.Statement), true
+	}
+	s := &ast.Spawn{Pos: pos, Expr: ex}
+	if succ, toks := p.accept(token.EOL); !succ {
+		return p.errorStmt("Invalid token in spawn statement: %v", toks[len(toks)-1])
+	}
+	return s, false
 }
 
-func (p *parser) parseExprStmt(inWith bool) (ast.Statement, bool) {
-	ex, _ := p.parseExprList()
-	var assign ast.Statement
-	if p.peek().Type.IsAssign() {
-		assign = p.parseAssignStmt(ex)
+func (p *parser) parseSelectStmt() (ast.Statement, bool) {
+	pos := p.next().Pos // eat select
+	sel := &ast.Select{Pos: pos}
+	if succ, tok := p.startBlock(false); !succ {
+		return p.errorStmt("Invalid token in select statement: %v", tok)
 	}
-	if !inWith {
-		if succ, toks := p.accept(token.EOL); !succ {
-			return p.errorStmt(true, "Invalid token in expression statement: %v", toks[len(toks)-1])
+	p.parseBlock("select statement", p.parseSelectCase, sel.AddStmt)
+	return sel, false
+}
+
+// parseSelectCase parses an is case of a select, which sends, receives or,
+// with is _, runs when no other case is ready.
+func (p *parser) parseSelectCase() (ast.Statement, bool) {
+	succ, toks := p.accept(token.IS)
+	if !succ {
+		return p.errorStmt("Invalid token in select statement: %v", toks[len(toks)-1])
+	}
+	sc := &ast.SelectCase{Pos: toks[0].Pos}
+
+	var comm ast.Statement
+	var commErr bool
+	switch p.peek().Type {
+	case token.BLANK:
+		p.next() // eat _
+	case token.VAR:
+		comm, commErr = p.parseVarStmt(true)
+	default:
+		comm, commErr = p.parseExprStmt(true)
+	}
+	if succ, tok := p.startBlock(commErr); !succ {
+		if commErr {
+			return comm, true
 		}
+		return p.errorStmt("Invalid token in select case: %v", tok)
 	}
-	if assign != nil {
-		return assign, false
+	if commErr {
+		sc.AddStmt(comm)
+	} else {
+		sc.Comm = comm
 	}
-	return &ast.ExprStmt{Expr: ex}, false
+
+	p.parseBlock("select case", p.parseFuncStmt, sc.AddStmt)
+
+	return sc, false
 }
 
-func (p *parser) parseAssignStmt(lhs ast.Expression) ast.Statement {
+func (p *parser) parseAssignStmt(lhs ast.Expression) (ast.Statement, bool) {
 	op := p.next().Type
-	rhs, _ := p.parseExprList()
-	return &ast.Assign{Op: op, Left: lhs, Right: rhs}
+	rhs, err := p.parseExprList()
+	if err {
+		return rhs.(ast.Statement), true
+	}
+	return &ast.Assign{Pos: ast.PosOf(lhs), Op: op, Left: lhs, Right: rhs}, false
 }
 
 func (p *parser) parseReturnStmt() (ast.Statement, bool) {
-	p.next() // eat ret
-	r := &ast.Return{}
+	pos := p.next().Pos // eat ret
+	r := &ast.Return{Pos: pos}
 	if p.peek().Type != token.EOL {
-		r.Vals, _ = p.parseExprList()
+		vals, err := p.parseExprList()
+		if err {
+			return vals.(ast.Statement), true
+		}
+		r.Vals = vals
 	}
 	if succ, toks := p.accept(token.EOL); !succ {
-		r.Vals, _ = p.errorExpr(true, "Invalid token in return statement: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in return statement: %v", toks[len(toks)-1])
 	}
 	return r, false
 }
 
 func (p *parser) parseDeferStmt() (ast.Statement, bool) {
-	p.next() // eat defer
-	ex, _ := p.parseExpr()
-	d := &ast.Defer{Expr: ex}
+	pos := p.next().Pos // eat defer
+	ex, err := p.parseExpr()
+	if err {
+		return ex.(ast.Statement), true
+	}
+	d := &ast.Defer{Pos: pos, Expr: ex}
 	if succ, toks := p.accept(token.EOL); !succ {
-		d.Expr, _ = p.errorExpr(true, "Invalid token in defer statement: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in defer statement: %v", toks[len(toks)-1])
 	}
 	return d, false
 }
 
 func (p *parser) parseVarStmt(inWith bool) (ast.Statement, bool) {
-	p.next() // eat var
-	vs := &ast.VarSet{}
+	pos := p.next().Pos // eat var
+	vs := &ast.VarSet{Pos: pos}
 
 	vsl, err := p.parseVarLineStmt(inWith)
 	if err {
@@ -636,7 +1078,7 @@ func (p *parser) parseVarStmt(inWith bool) (ast.Statement, bool) {
 			}
 
 			if succ, toks := p.accept(token.DEDENT, token.EOL); !succ {
-				return p.errorStmt(true, "Invalid token in var statement: %v", toks[len(toks)-1])
+				return p.errorStmt("Invalid token in var statement: %v", toks[len(toks)-1])
 			}
 		}
 	}
@@ -645,16 +1087,20 @@ func (p *parser) parseVarStmt(inWith bool) (ast.Statement, bool) {
 }
 
 func (p *parser) parseVarLineStmt(inWith bool) (ast.Statement, bool) {
-	v := &ast.VarSetLine{}
+	v := &ast.VarSetLine{Pos: p.peek().Pos}
 	for {
 		if p.peek().Type != token.IDENTIFIER && p.peek().Type != token.BLANK {
-			return p.errorStmt(true, "Invalid token in var statement: %v", p.peek())
+			return p.errorStmt("Invalid token in var statement: %v", p.peek())
 		}
 		name := p.next().Val
 
 		var typ ast.Statement
 		if p.peek().Type.IsType() {
-			typ, _ = p.parseType()
+			var err bool
+			typ, err = p.parseType()
+			if err {
+				return typ, true
+			}
 		}
 
 		v.AddVar(name, typ)
@@ -664,85 +1110,107 @@ func (p *parser) parseVarLineStmt(inWith bool) (ast.Statement, bool) {
 	}
 
 	if succ, _ := p.accept(token.ASSIGN); succ {
-		v.Vals, _ = p.parseExprList()
+		vals, err := p.parseExprList()
+		if err {
+			return vals.(ast.Statement), true
+		}
+		v.Vals = vals
 	}
 
 	if !inWith {
 		if succ, toks := p.accept(token.EOL); !succ {
-			return p.errorStmt(true, "Invalid token in var statement: %v", toks[len(toks)-1])
+			return p.errorStmt("Invalid token in var statement: %v", toks[len(toks)-1])
 		}
 	}
 	return v, false
 }
 
+// parseExprList parses one or more comma-separated expressions. On error the
+// returned expression is the error itself.
 func (p *parser) parseExprList() (ast.Expression, bool) {
-	el := &ast.ExprList{}
-loop:
+	return p.parseExprListFrom(p.peek().Pos, nil)
+}
+
+// parseExprListFrom parses an expression list at pos whose first expression
+// starts with the already parsed operand first, if not nil.
+func (p *parser) parseExprListFrom(pos token.Position, first ast.Expression) (ast.Expression, bool) {
+	el := &ast.ExprList{Pos: pos}
 	for {
-		e, err := p.parseExpr()
-		el.AddExpr(e)
+		var e ast.Expression
+		var err bool
+		if first != nil {
+			e, err = p.parseBinopRHS(0, first)
+			first = nil
+		} else {
+			e, err = p.parseExpr()
+		}
 		if err {
-			break loop
+			return e, true
 		}
+		el.AddExpr(e)
 		if succ, _ := p.accept(token.COMMA); !succ {
-			break loop
+			break
 		}
 	}
 	return el, false
 }
 
 func (p *parser) parseMLExprList(start, end token.Type) (ast.Expression, bool) {
-	el := &ast.ExprList{}
+	return p.parseMLList(start, end, p.parseExpr)
+}
+
+// parseMLList parses the elements between start and end, either all on one
+// line or on indented lines, into an expression list.
+func (p *parser) parseMLList(start, end token.Type, parseElem func() (ast.Expression, bool)) (ast.Expression, bool) {
+	el := &ast.ExprList{Pos: p.peek().Pos}
 	if succ, toks := p.accept(start); !succ {
-		ex, _ := p.errorExpr(true, "Invalid token in expression list: %v", toks[len(toks)-1])
-		el.AddExpr(ex)
-		return el, true
+		return p.errorExpr("Invalid token in expression list: %v", toks[len(toks)-1])
 	}
 	switch p.peek().Type {
 	case end: // do nothing
 	default:
 		if succ, _ := p.accept(token.EOL, token.INDENT); succ {
-		loop:
 			for {
-				e, err := p.parseExpr()
-				el.AddExpr(e)
+				e, err := parseElem()
 				if err {
-					break loop
+					return e, true
 				}
+				el.AddExpr(e)
 				if succ, _ := p.accept(token.EOL, token.DEDENT, token.EOL); succ {
-					break loop
+					break
 				}
 				if succ, toks := p.accept(token.COMMA); !succ {
-					ex, _ := p.errorExpr(true, "Invalid token in expression list: %v", toks[len(toks)-1])
-					el.AddExpr(ex)
-					break loop
+					return p.errorExpr("Invalid token in expression list: %v", toks[len(toks)-1])
 				}
 				p.accept(token.EOL) // eat EOL if it's there
 			}
 		} else {
-			ex, err := p.parseExprList()
-			if err {
-				el.AddExpr(ex)
-				return el, true
+			for {
+				e, err := parseElem()
+				if err {
+					return e, true
+				}
+				el.AddExpr(e)
+				if succ, _ := p.accept(token.COMMA); !succ {
+					break
+				}
 			}
-			el = ex.(*ast.ExprList)
 		}
 	}
 	if succ, toks := p.accept(end); !succ {
-		ex, _ := p.errorExpr(true, "Invalid token in expression list: %v", toks[len(toks)-1])
-		el.AddExpr(ex)
+		return p.errorExpr("Invalid token in expression list: %v", toks[len(toks)-1])
 	}
 	return el, false
 }
 
 func (p *parser) parseClassStmtIdent() (ast.Statement, bool) {
-	ps := &ast.PropertySet{}
+	ps := &ast.PropertySet{Pos: p.peek().Pos}
 
 	for {
 		dotted, _ := p.accept(token.DOT)
 		succ, toks := p.accept(token.IDENTIFIER)
 		if !succ {
-			return p.errorStmt(true, "Invalid token in class statement: %v", toks[len(toks)-1])
+			return p.errorStmt("Invalid token in class statement: %v", toks[len(toks)-1])
 		}
 		name := toks[0].Val
 
@@ -751,7 +1219,11 @@ func (p *parser) parseClassStmtIdent() (ast.Statement, bool) {
 		case t == token.LEFT_PAREN:
 			return p.parseFuncDef(dotted, name)
 		case t.IsType():
-			typ, _ = p.parseType()
+			var err bool
+			typ, err = p.parseType()
+			if err {
+				return typ, true
+			}
 		}
 
 		ps.AddProp(!dotted, name, typ)
@@ -761,34 +1233,46 @@ func (p *parser) parseClassStmtIdent() (ast.Statement, bool) {
 	}
 
 	if succ, _ := p.accept(token.ASSIGN); succ {
-		ps.Vals, _ = p.parseExprList()
+		vals, err := p.parseExprList()
+		if err {
+			return vals.(ast.Statement), true
+		}
+		ps.Vals = vals
 	}
 
 	if succ, toks := p.accept(token.EOL); !succ {
-		return p.errorStmt(true, "Invalid token in class statement: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in class statement: %v", toks[len(toks)-1])
 	}
 
 	return ps, false
 }
 
+// parseReturnValues parses the optional return type(s) of a function. On
+// error the last returned statement is the error.
 func (p *parser) parseReturnValues() ([]ast.Statement, bool) {
 	rvs := make([]ast.Statement, 0, 1)
 
 	switch t := p.peek().Type; {
 	case t.IsType():
-		st, _ := p.parseType()
+		st, err := p.parseType()
 		rvs = append(rvs, st)
+		if err {
+			return rvs, true
+		}
 	case t == token.LEFT_PAREN:
 		p.next() // eat (
 		for p.peek().Type != token.RIGHT_PAREN {
-			st, _ := p.parseType()
+			st, err := p.parseType()
 			rvs = append(rvs, st)
+			if err {
+				return rvs, true
+			}
 			switch p.peek().Type {
 			case token.COMMA:
 				p.next() // eat ,
 			case token.RIGHT_PAREN:
 			default:
-				st, _ := p.errorStmt(true, "Invalid token in return types: %v", p.peek())
+				st, _ := p.errorStmt("Invalid token in return types: %v", p.peek())
 				return append(rvs, st), true
 			}
 		}
@@ -807,19 +1291,75 @@ func (p *parser) parseAnonFuncExpr() (ast.Expression, bool) {
 // parseFuncDef parses a function definition with the optional dot and name
 // already consumed.
 func (p *parser) parseFuncDef(dotted bool, name string) (ast.Statement, bool) {
-	if succ, toks := p.accept(token.LEFT_PAREN); !succ {
-		return p.errorStmt(true, "Invalid token in function definition: %v", toks[len(toks)-1])
+	succ, toks := p.accept(token.LEFT_PAREN)
+	if !succ {
+		return p.errorStmt("Invalid token in function definition: %v", toks[len(toks)-1])
+	}
+	f := &ast.FunctionDef{Pos: toks[0].Pos, Static: !dotted, Name: name}
+
+	hdr, hdrErr := p.parseFuncDefHeader(f)
+	if hdrErr && name == "" {
+		// An anonymous function is part of an expression, so the whole
+		// statement is skipped instead.
+		return hdr, true
+	}
+	if succ, tok := p.startBlock(hdrErr); !succ {
+		if hdrErr {
+			return hdr, true
+		}
+		return p.errorStmt("Invalid token in function definition: %v", tok)
+	}
+	if hdrErr {
+		f.AddStmt(hdr)
+	}
+
+	p.parseBlock("function definition", p.parseFuncStmt, f.AddStmt)
+
+	// If it's an anonymous function and we're not in the middle of a block
+	// (followed by either a ',' or ')' ) then put the EOL back.
+	if name == "" && !p.peek().Type.IsInBlock() {
+		p.backup(1)
+	}
+
+	return f, false
+}
+
+// parseNativeFunc parses the declaration of a function implemented by the
+// runtime, which is a function definition without a body.
+func (p *parser) parseNativeFunc() (ast.Statement, bool) {
+	p.next() // eat native
+	dotted, _ := p.accept(token.DOT)
+	succ, toks := p.accept(token.IDENTIFIER, token.LEFT_PAREN)
+	if !succ {
+		return p.errorStmt("Invalid token in native function: %v", toks[len(toks)-1])
+	}
+	f := &ast.FunctionDef{Pos: toks[1].Pos, Static: !dotted, Native: true, Name: toks[0].Val}
+
+	if hdr, err := p.parseFuncDefHeader(f); err {
+		return hdr, true
+	}
+	if succ, toks := p.accept(token.EOL); !succ {
+		return p.errorStmt("Invalid token in native function: %v", toks[len(toks)-1])
 	}
-	f := &ast.FunctionDef{Static: !dotted, Name: name}
+	return f, false
+}
+
+// parseFuncDefHeader parses the parameters and return values of a function,
+// with the opening parenthesis already consumed.
+func (p *parser) parseFuncDefHeader(f *ast.FunctionDef) (ast.Statement, bool) {
 	for p.peek().Type != token.RIGHT_PAREN {
 		succ, toks := p.accept(token.IDENTIFIER)
 		if !succ {
-			return p.errorStmt(true, "Invalid token in function definition: %v", p.peek())
+			return p.errorStmt("Invalid token in function definition: %v", p.peek())
 		}
 		name := toks[0].Val
 		var typ ast.Statement
 		if p.peek().Type.IsType() {
-			typ, _ = p.parseType()
+			var err bool
+			typ, err = p.parseType()
+			if err {
+				return typ, true
+			}
 		}
 		f.AddParam(name, typ)
 		switch p.peek().Type {
@@ -827,132 +1367,123 @@ func (p *parser) parseFuncDef(dotted bool, name string) (ast.Statement, bool) {
 			p.next() // eat ,
 		case token.RIGHT_PAREN:
 		default:
-			return p.errorStmt(true, "Invalid token in function definition: %v", p.peek())
+			return p.errorStmt("Invalid token in function definition: %v", p.peek())
 		}
 	}
 	if succ, toks := p.accept(token.RIGHT_PAREN); !succ {
-		return p.errorStmt(true, "Invalid token in function definition: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in function definition: %v", toks[len(toks)-1])
 	}
 
 	// return value(s)
-	rvs, _ := p.parseReturnValues()
+	rvs, err := p.parseReturnValues()
+	if err {
+		return rvs[len(rvs)-1], true
+	}
 	for _, rv := range rvs {
 		f.AddReturn(rv)
 	}
-
-	if succ, toks := p.accept(token.EOL, token.INDENT); !succ {
-		return p.errorStmt(true, "Invalid token in function definition: %v", toks[len(toks)-1])
-	}
-
-	for p.peek().Type != token.DEDENT && p.peek().Type != token.EOF {
-		st, _ := p.parseFuncStmt()
-		f.AddStmt(st)
-	}
-
-	if succ, toks := p.accept(token.DEDENT, token.EOL); !succ {
-		st, _ := p.errorStmt(true, "Invalid token in function definition: %v", toks[len(toks)-1])
-		f.AddStmt(st)
-	}
-
-	// If it's an anonymous function and we're not in the middle of a block
-	// (followed by either a ',' or ')' ) then put the EOL back.
-	if name == "" && !p.peek().Type.IsInBlock() {
-		p.backup(1)
-	}
-
-	return f, false
+	return nil, false
 }
 
 func (p *parser) parsePrimaryExpr() (ast.Expression, bool) {
 	var lhs ast.Expression
+	var err bool
 	switch p.peek().Type {
 	case token.LEFT_PAREN:
-		lhs, _ = p.parseParenExpr()
+		lhs, err = p.parseParenExpr()
 	case token.LEFT_CURLY:
-		lhs, _ = p.parseCurlyExpr()
+		lhs, err = p.parseCurlyExpr()
 	case token.LEFT_BRACKET:
-		lhs, _ = p.parseArrayCons()
+		lhs, err = p.parseArrayCons()
 	case token.IDENTIFIER:
-		lhs, _ = p.parseIdentExpr()
+		lhs, err = p.parseIdentExpr()
 	case token.IOTA:
-		lhs, _ = p.parseIotaExpr()
+		lhs, err = p.parseIotaExpr()
 	case token.BLANK:
-		lhs, _ = p.parseBlankExpr()
+		lhs, err = p.parseBlankExpr()
 	case token.STRING:
-		lhs, _ = p.parseStringExpr()
+		lhs, err = p.parseStringExpr()
 	case token.NUMBER:
-		lhs, _ = p.parseNumberExpr()
+		lhs, err = p.parseNumberExpr()
 	case token.CHAR:
-		lhs, _ = p.parseCharExpr()
+		lhs, err = p.parseCharExpr()
 	case token.TRUE, token.FALSE:
-		lhs, _ = p.parseBoolExpr()
+		lhs, err = p.parseBoolExpr()
+	case token.NIL:
+		lhs = &ast.Nil{Pos: p.next().Pos}
+	case token.ARROW:
+		lhs, err = p.parseReceiveExpr()
 	case token.FUNCTION:
-		lhs, _ = p.parseAnonFuncExpr()
+		lhs, err = p.parseAnonFuncExpr()
 	default:
 		if p.peek().Type.IsUnaryOp() {
-			lhs, _ = p.parseUnaryExpr()
+			lhs, err = p.parseUnaryExpr()
 		}
 	}
 
+	if err {
+		return lhs, true
+	}
 	if lhs != nil {
 		// Function calls, constructors and accessors.
-	loop:
 		for {
 			switch p.peek().Type {
 			case token.LEFT_BRACKET:
-				lhs, _ = p.parseAccessorStmt(lhs)
+				lhs, err = p.parseAccessorStmt(lhs)
 			case token.LEFT_CURLY:
-				lhs, _ = p.parseConstructor(lhs)
+				lhs, err = p.parseConstructor(lhs)
 			case token.LEFT_PAREN:
-				lhs, _ = p.parseFuncCallStmt(lhs)
+				lhs, err = p.parseFuncCallStmt(lhs)
+			case token.QUESTION:
+				lhs = &ast.Propagate{Pos: p.next().Pos, Expr: lhs}
 			default:
-				break loop
+				return lhs, false
+			}
+			if err {
+				return lhs, true
 			}
 		}
-		return lhs, false
 	}
 
-	return p.errorExpr(true, "Token is not an expression: %v", p.peek())
+	return p.errorExpr("Token is not an expression: %v", p.peek())
 }
 
 func (p *parser) parseConstructor(lhs ast.Expression) (ast.Expression, bool) {
 	p.next() // eat {
-	con := &ast.Constructor{Type: lhs}
+	con := &ast.Constructor{Pos: ast.PosOf(lhs), Type: lhs}
 	switch p.peek().Type {
 	case token.RIGHT_CURLY: // do nothing
 	default:
 		if succ, _ := p.accept(token.EOL, token.INDENT); succ {
-		l1:
 			for {
 				kv, err := p.parseKeyVal()
-				con.AddParam(kv)
 				if err {
-					break l1
+					return kv.(ast.Expression), true
 				}
+				con.AddParam(kv)
 				if succ, _ := p.accept(token.EOL, token.DEDENT, token.EOL); succ {
-					break l1
+					break
 				}
 				if succ, toks := p.accept(token.COMMA); !succ {
-					return p.errorExpr(true, "Invalid token in constructor: %v", toks[len(toks)-1])
+					return p.errorExpr("Invalid token in constructor: %v", toks[len(toks)-1])
 				}
 				p.accept(token.EOL) // eat EOL if it's there
 			}
 		} else {
-		l2:
 			for {
 				kv, err := p.parseKeyVal()
-				con.AddParam(kv)
 				if err {
-					break l2
+					return kv.(ast.Expression), true
 				}
+				con.AddParam(kv)
 				if succ, _ := p.accept(token.COMMA); !succ {
-					break l2
+					break
 				}
 			}
 		}
 	}
 	if succ, toks := p.accept(token.RIGHT_CURLY); !succ {
-		return p.errorExpr(true, "Invalid token in constructor: %v", toks[len(toks)-1])
+		return p.errorExpr("Invalid token in constructor: %v", toks[len(toks)-1])
 	}
 	return con, false
 }
@@ -960,33 +1491,71 @@ func (p *parser) parseConstructor(lhs ast.Expression) (ast.Expression, bool) {
 func (p *parser) parseKeyVal() (ast.Statement, bool) {
 	succ, toks := p.accept(token.IDENTIFIER, token.COLON)
 	if !succ {
-		return p.errorStmt(true, "Invalid token in key:value pair: %v", toks[len(toks)-1])
+		return p.errorStmt("Invalid token in key:value pair: %v", toks[len(toks)-1])
 	}
-	kv := &ast.KeyVal{Key: toks[0].Val}
+	kv := &ast.KeyVal{Pos: toks[0].Pos, Key: toks[0].Val}
 	ex, err := p.parseExpr()
+	if err {
+		return ex.(ast.Statement), true
+	}
 	kv.Val = ex
-	return kv, err
+	return kv, false
 }
 
 func (p *parser) parseArrayCons() (ast.Expression, bool) {
-	p.next() // eat [
+	pos := p.next().Pos // eat [
 	size, err := p.parseExpr()
 	if err {
 		return size, true
 	}
 	if succ, toks := p.accept(token.RIGHT_BRACKET); !succ {
-		return p.errorExpr(true, "Invalid token in array constructor: %v", toks[len(toks)-1])
+		return p.errorExpr("Invalid token in array constructor: %v", toks[len(toks)-1])
 	}
 	typ, err := p.parseType()
 	if err {
 		return typ.(ast.Expression), true
 	}
-	return &ast.ArrayCons{Type: typ, Size: size}, false
+	return &ast.ArrayCons{Pos: pos, Type: typ, Size: size}, false
 }
 
+// parseCurlyExpr parses an array value list, or a map literal if its values
+// are key: value entries.
 func (p *parser) parseCurlyExpr() (ast.Expression, bool) {
-	ex, err := p.parseMLExprList(token.LEFT_CURLY, token.RIGHT_CURLY)
-	return &ast.ArrayValueList{Vals: ex}, err
+	pos := p.peek().Pos
+	ex, err := p.parseMLList(token.LEFT_CURLY, token.RIGHT_CURLY, p.parseCurlyElem)
+	if err {
+		return ex, true
+	}
+	vals := ex.(*ast.ExprList).Exprs()
+	entries := 0
+	for _, v := range vals {
+		if _, ok := v.(*ast.MapEntry); ok {
+			entries++
+		}
+	}
+	switch entries {
+	case 0:
+		return &ast.ArrayValueList{Pos: pos, Vals: ex}, false
+	case len(vals):
+		return &ast.MapValueList{Pos: pos, Vals: ex}, false
+	}
+	return &ast.Error{Pos: pos, Val: "Map literal mixes key: value entries and values"}, true
+}
+
+// parseCurlyElem parses a value in braces, or a key: value entry.
+func (p *parser) parseCurlyElem() (ast.Expression, bool) {
+	key, err := p.parseExpr()
+	if err {
+		return key, true
+	}
+	if succ, _ := p.accept(token.COLON); !succ {
+		return key, false
+	}
+	val, err := p.parseExpr()
+	if err {
+		return val, true
+	}
+	return &ast.MapEntry{Pos: ast.PosOf(key), Key: key, Val: val}, false
 }
 
 func (p *parser) parseAccessorStmt(lhs ast.Expression) (ast.Expression, bool) {
@@ -1014,44 +1583,61 @@ func (p *parser) parseAccessorStmt(lhs ast.Expression) (ast.Expression, bool) {
 	}
 
 	if succ, toks := p.accept(token.RIGHT_BRACKET); !succ {
-		return p.errorExpr(true, "Invalid token in accessor: %v", toks[len(toks)-1])
+		return p.errorExpr("Invalid token in accessor: %v", toks[len(toks)-1])
 	}
 
 	if isRange {
-		return &ast.AccessorRange{Object: lhs, Low: low, High: high}, false
+		return &ast.AccessorRange{Pos: ast.PosOf(lhs), Object: lhs, Low: low, High: high}, false
 	}
-	return &ast.Accessor{Object: lhs, Index: low}, false
+	return &ast.Accessor{Pos: ast.PosOf(lhs), Object: lhs, Index: low}, false
 }
 
 func (p *parser) parseFuncCallStmt(lhs ast.Expression) (ast.Expression, bool) {
-	fc := &ast.FunctionCall{Function: lhs}
-	fc.Params, _ = p.parseMLExprList(token.LEFT_PAREN, token.RIGHT_PAREN)
-	return fc, false
+	params, err := p.parseMLExprList(token.LEFT_PAREN, token.RIGHT_PAREN)
+	if err {
+		return params, true
+	}
+	return &ast.FunctionCall{Pos: ast.PosOf(lhs), Function: lhs, Params: params}, false
+}
+
+func (p *parser) parseReceiveExpr() (ast.Expression, bool) {
+	pos := p.next().Pos // eat <-
+	ch, err := p.parsePrimaryExpr()
+	if err {
+		return ch, true
+	}
+	return &ast.Receive{Pos: pos, Chan: ch}, false
 }
 
 func (p *parser) parseUnaryExpr() (ast.Expression, bool) {
-	op := p.next().Type
-	ex, _ := p.parsePrimaryExpr()
-	return &ast.Unary{Expr: ex, Op: op}, false
+	op := p.next()
+	ex, err := p.parsePrimaryExpr()
+	if err {
+		return ex, true
+	}
+	return &ast.Unary{Pos: op.Pos, Expr: ex, Op: op.Type}, false
 }
 
 func (p *parser) parseParenExpr() (ast.Expression, bool) {
 	p.next() // eat (
-	expr, _ := p.parseExpr()
+	expr, err := p.parseExpr()
+	if err {
+		return expr, true
+	}
 	if succ, toks := p.accept(token.RIGHT_PAREN); !succ {
-		return p.errorExpr(true, "Invalid token in (): %v", toks[len(toks)-1])
+		return p.errorExpr("Invalid token in (): %v", toks[len(toks)-1])
 	}
 	return expr, false
 }
 
 func (p *parser) parseIdentExpr() (ast.Expression, bool) {
-	ie := &ast.Identifier{}
+	ie := &ast.Identifier{Pos: p.peek().Pos}
 	for {
 		succ, toks := p.accept(token.IDENTIFIER)
 		if !succ {
-			return p.errorExpr(true, "Invalid token in identifier: %v", toks[len(toks)-1])
+			return p.errorExpr("Invalid token in identifier: %v", toks[len(toks)-1])
 		}
-		ip := &ast.IdentPart{Name: toks[0].Val}
+		ip := &ast.IdentPart{Pos: toks[0].Pos, Name: toks[0].Val}
 		if succ, _ := p.accept(token.LEFT_CARET); succ {
 			resetPos := p.pos - 1 // store the position in case the caret isn't a generic
 			for p.peek().Type.IsType() {
@@ -1075,29 +1661,33 @@ func (p *parser) parseIdentExpr() (ast.Expression, bool) {
 }
 
 func (p *parser) parseBoolExpr() (ast.Expression, bool) {
-	return &ast.Bool{Val: p.next().Type == token.TRUE}, false
+	t := p.next()
+	return &ast.Bool{Pos: t.Pos, Val: t.Type == token.TRUE}, false
 }
 
 func (p *parser) parseCharExpr() (ast.Expression, bool) {
-	return &ast.Char{Val: p.next().Val}, false
+	t := p.next()
+	return &ast.Char{Pos: t.Pos, Val: t.Val}, false
 }
 
 func (p *parser) parseNumberExpr() (ast.Expression, bool) {
-	return &ast.Number{Val: p.next().Val}, false
+	t := p.next()
+	return &ast.Number{Pos: t.Pos, Val: t.Val}, false
 }
 
 func (p *parser) parseIotaExpr() (ast.Expression, bool) {
-	p.next() // eat iota
-	return &ast.Iota{}, false
+	pos := p.next().Pos // eat iota
+	return &ast.Iota{Pos: pos}, false
 }
 
 func (p *parser) parseBlankExpr() (ast.Expression, bool) {
-	p.next() // eat _
-	return &ast.Blank{}, false
+	pos := p.next().Pos // eat _
+	return &ast.Blank{Pos: pos}, false
 }
 
 func (p *parser) parseStringExpr() (ast.Expression, bool) {
-	return &ast.String{Val: p.next().Val}, false
+	t := p.next()
+	return &ast.String{Pos: t.Pos, Val: t.Val}, false
 }
 
 func (p *parser) parseExpr() (ast.Expression, bool) {
@@ -1136,19 +1726,20 @@ func (p *parser) parseBinopRHS(exprPrec int, lhs ast.Expression) (ast.Expression
 		}
 
 		// Merge lhs/rhs
-		lhs = &ast.Binary{Op: op.Type, Left: lhs, Right: rhs}
+		lhs = &ast.Binary{Pos: ast.PosOf(lhs), Op: op.Type, Left: lhs, Right: rhs}
 	}
 }
 
 func (p *parser) parseIotaStmt() (ast.Statement, bool) {
-	if succ, toks := p.accept(token.IOTA, token.EOL); !succ {
-		return p.errorStmt(true, "Invalid token in iota reset: %v", toks[len(toks)-1])
+	succ, toks := p.accept(token.IOTA, token.EOL)
+	if !succ {
+		return p.errorStmt("Invalid token in iota reset: %v", toks[len(toks)-1])
 	}
-	return &ast.Iota{}, false
+	return &ast.Iota{Pos: toks[0].Pos}, false
 }
 
 func (p *parser) parseTypeIdent() (ast.Statement, bool) {
-	t := &ast.TypeIdent{}
+	t := &ast.TypeIdent{Pos: p.peek().Pos}
 	t.AddIdent(p.next().Val)
 	for {
 		succ, toks := p.accept(token.DOT, token.IDENTIFIER)
@@ -1159,42 +1750,52 @@ func (p *parser) parseTypeIdent() (ast.Statement, bool) {
 	}
 	if succ, _ := p.accept(token.LEFT_CARET); succ {
 		for p.peek().Type.IsType() {
-			st, _ := p.parseType()
+			st, err := p.parseType()
+			if err {
+				return st, true
+			}
 			t.AddTypeParam(st)
 			if succ, _ = p.accept(token.COMMA); !succ {
 				break
 			}
 		}
 		if succ, toks := p.accept(token.RIGHT_CARET); !succ {
-			return p.errorStmt(true, "Invalid token parsing type identifier: %v", toks[len(toks)-1])
+			return p.errorStmt("Invalid token parsing type identifier: %v", toks[len(toks)-1])
 		}
 	}
 	return t, false
 }
 
-func (p *parser) parseUse() (ast.Statement, bool) {
-	p.next() // eat token.USE
-	u := &ast.Use{}
+// parseUse parses a use statement, returning it followed by an error for each
+// package line that couldn't be parsed.
+func (p *parser) parseUse() []ast.Statement {
+	start := p.pos
+	pos := p.next().Pos // eat token.USE
+	u := &ast.Use{Pos: pos}
 
 	err, pack, alias, errTok := p.parseUsePackage()
 	if err {
-		return p.errorStmt(true, "Invalid token found when parsing Use: %v", errTok)
+		st, _ := p.errorStmt("Invalid token found when parsing Use: %v", errTok)
+		p.skipStmt(start)
+		return []ast.Statement{st}
 	}
 	u.AddPackage(pack, alias)
 
+	stmts := []ast.Statement{u}
 	if succ, _ := p.accept(token.INDENT); succ {
-		err, pack, alias, errTok := p.parseUsePackage()
-		for !err {
+		p.parseBlock("Use", func() (ast.Statement, bool) {
+			err, pack, alias, errTok := p.parseUsePackage()
+			if err {
+				return p.errorStmt("Invalid token found when parsing Use: %v", errTok)
+			}
 			u.AddPackage(pack, alias)
-			err, pack, alias, errTok = p.parseUsePackage()
-		}
-		if succ, _ = p.accept(token.DEDENT, token.EOL); succ {
-			return u, false
-		}
-		return p.errorStmt(true, "Invalid token found when parsing Use: %v", errTok)
+			return nil, false
+		}, func(st ast.Statement) {
+			stmts = append(stmts, st)
+		})
 	}
 
-	return u, false
+	return stmts
 }
 
 func (p *parser) parseUsePackage() (bool, string, string, token.Token) {
@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/lexer"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "regenerate the .tokens and .ast golden files in testdata")
+
+// TestGolden lexes and parses every testdata/*.char file and compares the
+// token stream and AST dump against the matching .tokens and .ast files.
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.char"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no testdata/*.char files found")
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".char")
+		t.Run(name, func(t *testing.T) {
+			dat, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens := lexer.LexAll(string(dat))
+
+			var tokBuf bytes.Buffer
+			for _, tok := range tokens {
+				fmt.Fprint(&tokBuf, " ", tok)
+			}
+			tokBuf.WriteString("\n")
+			checkGolden(t, strings.TrimSuffix(file, ".char")+".tokens", tokBuf.Bytes())
+
+			fAST, _ := ParseTokens(filepath.ToSlash(file), tokens)
+			var astBuf bytes.Buffer
+			ast.Fprint(&astBuf, fAST, 0)
+			checkGolden(t, strings.TrimSuffix(file, ".char")+".ast", astBuf.Bytes())
+		})
+	}
+}
+
+func checkGolden(t *testing.T, golden string, got []byte) {
+	if *update {
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%v differs from the current output\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+	}
+}
+
+// FuzzParse checks that parsing any input finishes and produces an AST,
+// with problems reported as ast.Error nodes rather than panics.
+func FuzzParse(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.char"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		dat, err := ioutil.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(dat))
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		done := make(chan ast.General)
+		go func() {
+			fAST, _ := ParseTokens("fuzz.char", lexer.LexAll(input))
+			done <- fAST
+		}()
+
+		select {
+		case fAST := <-done:
+			if fAST == nil {
+				t.Fatalf("parsing %q returned no AST", input)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("parsing %q did not finish", input)
+		}
+	})
+}
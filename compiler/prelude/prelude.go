@@ -0,0 +1,54 @@
+// Package prelude holds the Char source of the prelude, the package every
+// Char package can use without declaring it. It is built into the compiler so
+// that it always matches the compiler's version.
+package prelude
+
+import (
+	"embed"
+	"github.com/defiant00/char/compiler/ast"
+	"github.com/defiant00/char/compiler/lexer"
+	"github.com/defiant00/char/compiler/parser"
+	"path"
+	"sort"
+)
+
+// Name is the name of the prelude package.
+const Name = "prelude"
+
+//go:embed *.char
+var sources embed.FS
+
+// Files parses the prelude, returning its files sorted by name. The prelude
+// always parses, so errors panic.
+func Files() []*ast.File {
+	entries, err := sources.ReadDir(".")
+	if err != nil {
+		panic(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+
+	var files []*ast.File
+	for _, name := range names {
+		src, err := sources.ReadFile(name)
+		if err != nil {
+			panic(err)
+		}
+		fAST, _ := parser.ParseTokens(path.Join(Name, name), lexer.LexAll(string(src)))
+		f, ok := fAST.(*ast.File)
+		ast.Inspect(fAST, func(n ast.General) bool {
+			if _, isErr := n.(*ast.Error); isErr {
+				ok = false
+			}
+			return ok
+		})
+		if !ok {
+			panic("prelude: " + name + " doesn't parse")
+		}
+		files = append(files, f)
+	}
+	return files
+}
@@ -8,6 +8,11 @@ func (t Type) String() string {
 	return tStrings[t]
 }
 
+// MarshalText encodes the type as its display string, e.g. "id" or "<<=".
+func (t Type) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
 func (t Type) IsKeyword() bool {
 	return t > keyword_start && t < keyword_end
 }
@@ -45,10 +50,14 @@ const (
 	IF                         // 'if'
 	IS                         // 'is'
 	IN                         // 'in'
+	TO                         // 'to'
 	WITH                       // 'with'
 	FUNCTION                   // 'fn'
 	INTERFACE                  // 'intf'
 	MIXIN                      // 'mix'
+	ENUM                       // 'enum'
+	PRIV                       // 'priv'
+	NATIVE                     // 'native'
 	VAR                        // 'var'
 	BLANK                      // '_'
 	RETURN                     // 'ret'
@@ -56,9 +65,12 @@ const (
 	FOR                        // 'for'
 	LOOP                       // 'loop'
 	BREAK                      // 'break'
+	SPAWN                      // 'spawn'
+	SELECT                     // 'select'
 	IOTA                       // 'iota'
 	TRUE                       // 'true'
 	FALSE                      // 'false'
+	NIL                        // 'nil'
 	EQUAL                      // '=='
 	NOT_EQUAL                  // '!='
 	LEFT_CARET                 // '<'
@@ -77,6 +89,8 @@ const (
 	ARRAY                      // '[]'
 	LEFT_CURLY                 // '{'
 	RIGHT_CURLY                // '}'
+	QUESTION                   // '?'
+	ARROW                      // '<-'
 	assign_start               //
 	ASSIGN                     // '='
 	ADD_ASSIGN                 // '+='
@@ -122,10 +136,14 @@ var tStrings = map[Type]string{
 	IF:            "if",
 	IS:            "is",
 	IN:            "in",
+	TO:            "to",
 	WITH:          "with",
 	FUNCTION:      "fn",
 	INTERFACE:     "intf",
 	MIXIN:         "mix",
+	ENUM:          "enum",
+	PRIV:          "priv",
+	NATIVE:        "native",
 	VAR:           "var",
 	BLANK:         "_",
 	RETURN:        "ret",
@@ -133,9 +151,12 @@ var tStrings = map[Type]string{
 	FOR:           "for",
 	LOOP:          "loop",
 	BREAK:         "break",
+	SPAWN:         "spawn",
+	SELECT:        "select",
 	IOTA:          "iota",
 	TRUE:          "true",
 	FALSE:         "false",
+	NIL:           "nil",
 	EQUAL:         "==",
 	NOT_EQUAL:     "!=",
 	LEFT_CARET:    "<",
@@ -154,6 +175,8 @@ var tStrings = map[Type]string{
 	ARRAY:         "[]",
 	LEFT_CURLY:    "{",
 	RIGHT_CURLY:   "}",
+	QUESTION:      "?",
+	ARROW:         "<-",
 	ASSIGN:        "=",
 	ADD_ASSIGN:    "+=",
 	SUB_ASSIGN:    "-=",
@@ -179,70 +202,79 @@ var tStrings = map[Type]string{
 }
 
 var Keywords = map[string]Type{
-	"use":   USE,
-	"as":    AS,
-	"if":    IF,
-	"is":    IS,
-	"in":    IN,
-	"with":  WITH,
-	"fn":    FUNCTION,
-	"intf":  INTERFACE,
-	"mix":   MIXIN,
-	"var":   VAR,
-	"_":     BLANK,
-	"ret":   RETURN,
-	"defer": DEFER,
-	"for":   FOR,
-	"loop":  LOOP,
-	"break": BREAK,
-	"iota":  IOTA,
-	"true":  TRUE,
-	"false": FALSE,
-	"==":    EQUAL,
-	"!=":    NOT_EQUAL,
-	"<":     LEFT_CARET,
-	">":     RIGHT_CARET,
-	"<=":    LT_EQUAL,
-	">=":    GT_EQUAL,
-	"and":   AND,
-	"or":    OR,
-	".":     DOT,
-	",":     COMMA,
-	":":     COLON,
-	"(":     LEFT_PAREN,
-	")":     RIGHT_PAREN,
-	"[":     LEFT_BRACKET,
-	"]":     RIGHT_BRACKET,
-	"[]":    ARRAY,
-	"{":     LEFT_CURLY,
-	"}":     RIGHT_CURLY,
-	"=":     ASSIGN,
-	"+=":    ADD_ASSIGN,
-	"-=":    SUB_ASSIGN,
-	"*=":    MUL_ASSIGN,
-	"/=":    DIV_ASSIGN,
-	"%=":    MOD_ASSIGN,
-	"&=":    B_AND_ASSIGN,
-	"|=":    B_OR_ASSIGN,
-	"^=":    B_XOR_ASSIGN,
-	"<<=":   LSHIFT_ASSIGN,
-	">>=":   RSHIFT_ASSIGN,
-	"+":     ADD,
-	"-":     SUB,
-	"*":     MUL,
-	"/":     DIV,
-	"%":     MOD,
-	"<<":    LSHIFT, // RSHIFT purposefully excluded since it clashes
-	"&":     B_AND,  // with generics. See parser.peekCombo and
-	"|":     B_OR,   // parser.nextCombo for where this is handled.
-	"^":     B_XOR,
-	"!":     NOT,
+	"use":    USE,
+	"as":     AS,
+	"if":     IF,
+	"is":     IS,
+	"in":     IN,
+	"to":     TO,
+	"with":   WITH,
+	"fn":     FUNCTION,
+	"intf":   INTERFACE,
+	"mix":    MIXIN,
+	"enum":   ENUM,
+	"priv":   PRIV,
+	"native": NATIVE,
+	"var":    VAR,
+	"_":      BLANK,
+	"ret":    RETURN,
+	"defer":  DEFER,
+	"for":    FOR,
+	"loop":   LOOP,
+	"break":  BREAK,
+	"spawn":  SPAWN,
+	"select": SELECT,
+	"iota":   IOTA,
+	"true":   TRUE,
+	"false":  FALSE,
+	"nil":    NIL,
+	"==":     EQUAL,
+	"!=":     NOT_EQUAL,
+	"<":      LEFT_CARET,
+	">":      RIGHT_CARET,
+	"<=":     LT_EQUAL,
+	">=":     GT_EQUAL,
+	"and":    AND,
+	"or":     OR,
+	".":      DOT,
+	",":      COMMA,
+	":":      COLON,
+	"(":      LEFT_PAREN,
+	")":      RIGHT_PAREN,
+	"[":      LEFT_BRACKET,
+	"]":      RIGHT_BRACKET,
+	"[]":     ARRAY,
+	"{":      LEFT_CURLY,
+	"}":      RIGHT_CURLY,
+	"?":      QUESTION,
+	"<-":     ARROW,
+	"=":      ASSIGN,
+	"+=":     ADD_ASSIGN,
+	"-=":     SUB_ASSIGN,
+	"*=":     MUL_ASSIGN,
+	"/=":     DIV_ASSIGN,
+	"%=":     MOD_ASSIGN,
+	"&=":     B_AND_ASSIGN,
+	"|=":     B_OR_ASSIGN,
+	"^=":     B_XOR_ASSIGN,
+	"<<=":    LSHIFT_ASSIGN,
+	">>=":    RSHIFT_ASSIGN,
+	"+":      ADD,
+	"-":      SUB,
+	"*":      MUL,
+	"/":      DIV,
+	"%":      MOD,
+	"<<":     LSHIFT, // RSHIFT purposefully excluded since it clashes
+	"&":      B_AND,  // with generics. See parser.peekCombo and
+	"|":      B_OR,   // parser.nextCombo for where this is handled.
+	"^":      B_XOR,
+	"!":      NOT,
 }
 
 type Token struct {
-	Type Type
-	Pos  Position
-	Val  string
+	Type Type     `json:"type"`
+	Pos  Position `json:"pos"`
+	Val  string   `json:"val"`
 }
 
 func (t Token) String() string {
@@ -277,7 +309,8 @@ func (t Token) Precedence() int {
 }
 
 type Position struct {
-	Line, Char int
+	Line int `json:"line"`
+	Char int `json:"char"`
 }
 
 func (p Position) String() string {
@@ -7,13 +7,14 @@ import (
 )
 
 func main() {
-	fmt.Println("Char Compiler v0.1")
-	var build, format, printTokens, printAST bool
+	var build, format, printTokens, printAST, asJSON bool
 	if len(os.Args) < 2 {
+		fmt.Println("Char Compiler v0.1")
 		fmt.Println("Usage: char <path> [parameters]")
 		return
 	}
 	path := os.Args[1]
+	var unknown []string
 	for _, a := range os.Args[2:] {
 		switch a {
 		case "-build":
@@ -24,10 +25,26 @@ func main() {
 			printTokens = true
 		case "-printAST":
 			printAST = true
+		case "-json":
+			asJSON = true
 		default:
-			fmt.Printf("Unknown parameter %v\n", a)
+			unknown = append(unknown, a)
+		}
+	}
+
+	// With -json, stdout holds nothing but the JSON document.
+	if asJSON {
+		for _, a := range unknown {
+			fmt.Fprintf(os.Stderr, "Unknown parameter %v\n", a)
 		}
+		compiler.Build(path, build, format, printTokens, printAST, true)
+		return
+	}
+
+	fmt.Println("Char Compiler v0.1")
+	for _, a := range unknown {
+		fmt.Printf("Unknown parameter %v\n", a)
 	}
-	compiler.Build(path, build, format, printTokens, printAST)
+	compiler.Build(path, build, format, printTokens, printAST, false)
 	fmt.Println("\nDone")
 }